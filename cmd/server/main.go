@@ -7,12 +7,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // per-user time zones must resolve even without system zoneinfo
 
 	"github.com/go-kit/log"
 	"github.com/joho/godotenv"
 	"github.com/meeting-scheduler/internal/endpoint"
 	"github.com/meeting-scheduler/internal/service"
 	"github.com/meeting-scheduler/internal/transport"
+	"github.com/meeting-scheduler/pkg/algorithm"
 	"github.com/meeting-scheduler/pkg/repository"
 )
 
@@ -35,22 +37,25 @@ func main() {
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 	logger = log.With(logger, "caller", log.DefaultCaller)
 
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "3306")
-	dbUser := getEnv("DB_USER", "root")
-	dbPassword := getEnv("DB_PASSWORD", "root")
-	dbName := getEnv("DB_NAME", "meeting_scheduler")
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
-
-	repo, err := repository.NewMySQLRepository(dsn)
+	repo, err := newRepository(getEnv("STORAGE", "mysql"))
 	if err != nil {
 		logger.Log("error", err)
 		os.Exit(1)
 	}
 
-	svc := service.NewService(repo)
+	scorers := algorithm.NewRegistry()
+	if weights := os.Getenv("SCORING_WEIGHTS"); weights != "" {
+		overrides, err := algorithm.ParseWeightOverrides(weights)
+		if err == nil {
+			err = scorers.OverrideWeights(overrides)
+		}
+		if err != nil {
+			logger.Log("error", err)
+			os.Exit(1)
+		}
+	}
+
+	svc := service.NewService(repo, service.WithScorers(scorers))
 
 	endpoints := endpoint.MakeEndpoints(svc)
 
@@ -73,6 +78,28 @@ func main() {
 	logger.Log("exit", <-errs)
 }
 
+// newRepository creates the storage selected by STORAGE: "mysql", configured
+// by the DB_* variables, or "memory", which keeps data only while the server
+// runs
+func newRepository(storage string) (service.Repository, error) {
+	switch storage {
+	case "memory":
+		return repository.NewMemoryRepository(), nil
+	case "mysql":
+		dbHost := getEnv("DB_HOST", "localhost")
+		dbPort := getEnv("DB_PORT", "3306")
+		dbUser := getEnv("DB_USER", "root")
+		dbPassword := getEnv("DB_PASSWORD", "root")
+		dbName := getEnv("DB_NAME", "meeting_scheduler")
+
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			dbUser, dbPassword, dbHost, dbPort, dbName)
+		return repository.NewMySQLRepository(dsn)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE %q, expected \"mysql\" or \"memory\"", storage)
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
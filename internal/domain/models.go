@@ -1,28 +1,244 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Default working hours for users who have not configured their own
+const (
+	DefaultWorkdayStart = 9  // 9 AM
+	DefaultWorkdayEnd   = 17 // 5 PM
+)
+
+// Upper bounds on the buffers kept free around meetings and on the travel
+// time around events
+const (
+	MaxBufferMinutes = 120
+	MaxTravelMinutes = 240
+)
+
 // User represents a participant who can be scheduled for meetings
 type User struct {
-	ID        string    `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID   string `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	// TimeZone is an IANA time zone name such as "Asia/Tokyo"; empty means UTC
+	TimeZone string `json:"timeZone"`
+	// WorkdayStart and WorkdayEnd are the local hours (0-24) the user works
+	// between; both zero means DefaultWorkdayStart to DefaultWorkdayEnd
+	WorkdayStart int `json:"workdayStart"`
+	WorkdayEnd   int `json:"workdayEnd"`
+	// BufferBeforeMinutes and BufferAfterMinutes are the free time the user
+	// needs before and after every meeting booked for them
+	BufferBeforeMinutes int       `json:"bufferBeforeMinutes"`
+	BufferAfterMinutes  int       `json:"bufferAfterMinutes"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+// Location returns the user's time zone, falling back to UTC if it is unset or unknown
+func (u User) Location() *time.Location {
+	return loadLocation(u.TimeZone)
+}
+
+// loadLocation returns the named IANA time zone, or UTC if the name is empty
+// or unknown
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// WorkingHours returns the local hours the user's working day starts and ends at
+func (u User) WorkingHours() (start, end int) {
+	if u.WorkdayStart == 0 && u.WorkdayEnd == 0 {
+		return DefaultWorkdayStart, DefaultWorkdayEnd
+	}
+	return u.WorkdayStart, u.WorkdayEnd
+}
+
+// CreateUserRequest represents the input for creating a user
+type CreateUserRequest struct {
+	Name         string `json:"name"`
+	TimeZone     string `json:"timeZone,omitempty"`
+	WorkdayStart int    `json:"workdayStart,omitempty"`
+	WorkdayEnd   int    `json:"workdayEnd,omitempty"`
+
+	BufferBeforeMinutes int `json:"bufferBeforeMinutes,omitempty"`
+	BufferAfterMinutes  int `json:"bufferAfterMinutes,omitempty"`
+}
+
+// UpdateUserRequest represents a partial update of a user; nil fields are left unchanged
+type UpdateUserRequest struct {
+	Name         *string `json:"name,omitempty"`
+	TimeZone     *string `json:"timeZone,omitempty"`
+	WorkdayStart *int    `json:"workdayStart,omitempty"`
+	WorkdayEnd   *int    `json:"workdayEnd,omitempty"`
+
+	BufferBeforeMinutes *int `json:"bufferBeforeMinutes,omitempty"`
+	BufferAfterMinutes  *int `json:"bufferAfterMinutes,omitempty"`
+}
+
+// Kinds of resources
+const (
+	ResourceRoom      = "room"
+	ResourceEquipment = "equipment"
+)
+
+// Resource is a meeting room or a piece of equipment that is booked together
+// with a meeting. Like a user it has its own calendar.
+type Resource struct {
+	ID   string `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	// Kind is ResourceRoom or ResourceEquipment
+	Kind string `json:"kind" gorm:"index"`
+	// Capacity is the number of seats of a room
+	Capacity int    `json:"capacity"`
+	Building string `json:"building,omitempty" gorm:"index"`
+	// Attributes are features such as "projector" or "whiteboard"
+	Attributes []string  `json:"attributes,omitempty" gorm:"serializer:json;type:text"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// CreateResourceRequest represents the input for creating a resource
+type CreateResourceRequest struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind,omitempty"`
+	Capacity   int      `json:"capacity,omitempty"`
+	Building   string   `json:"building,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+}
+
+// ResourceRequirement asks for any one resource that meets all of its
+// conditions, e.g. a room with at least 8 seats and a projector in building A.
+// Empty conditions match every resource.
+type ResourceRequirement struct {
+	Kind        string   `json:"kind,omitempty"`
+	MinCapacity int      `json:"minCapacity,omitempty"`
+	Building    string   `json:"building,omitempty"`
+	Attributes  []string `json:"attributes,omitempty"`
+}
+
+// Matches reports whether the resource meets the requirement. Buildings and
+// attributes are compared case-insensitively.
+func (r ResourceRequirement) Matches(resource Resource) bool {
+	if r.Kind != "" && r.Kind != resource.Kind {
+		return false
+	}
+	if resource.Capacity < r.MinCapacity {
+		return false
+	}
+	if r.Building != "" && !strings.EqualFold(r.Building, resource.Building) {
+		return false
+	}
+	for _, wanted := range r.Attributes {
+		found := false
+		for _, attribute := range resource.Attributes {
+			if strings.EqualFold(wanted, attribute) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// EventRequest represents the input for adding or changing an event on a user's calendar
+type EventRequest struct {
+	Title     string    `json:"title,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	// TravelMinutes is the time needed to get to and from the event
+	TravelMinutes int `json:"travelMinutes,omitempty"`
 }
 
-// CalendarEvent represents a scheduled meeting or event
+// CalendarEvent represents a scheduled meeting or event. A recurring event
+// is stored once, with StartTime and EndTime describing its first occurrence.
 type CalendarEvent struct {
 	ID        string    `json:"id" gorm:"primaryKey"`
 	Title     string    `json:"title"`
 	StartTime time.Time `json:"startTime"`
 	EndTime   time.Time `json:"endTime"`
 	UserID    string    `json:"userId" gorm:"index"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	MeetingID *string   `json:"meetingId,omitempty" gorm:"index"`
+	// ResourceID is set instead of UserID on the events that book a resource
+	// for a meeting
+	ResourceID *string `json:"resourceId,omitempty" gorm:"index"`
+	// TravelMinutes pads the event on both sides with the time needed to get
+	// there and back; meetings cannot be booked during it
+	TravelMinutes int `json:"travelMinutes,omitempty"`
+	// BufferBeforeMinutes and BufferAfterMinutes keep free time before and
+	// after a participant's meeting event, as the meeting was booked with;
+	// other meetings cannot be booked during them
+	BufferBeforeMinutes int `json:"bufferBeforeMinutes,omitempty"`
+	BufferAfterMinutes  int `json:"bufferAfterMinutes,omitempty"`
+	// UID is the iCalendar UID of an event imported from another calendar;
+	// re-importing the same UID updates the event instead of duplicating it
+	UID string `json:"uid,omitempty" gorm:"index"`
+	// Recurrence is an RFC 5545 recurrence rule; empty for single events
+	Recurrence string `json:"recurrence,omitempty"`
+	// TimeZone is the IANA time zone a recurring event repeats in, keeping its
+	// local time across daylight saving changes; empty means UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// Exceptions are the start times of occurrences that do not take place
+	Exceptions []time.Time `json:"exceptions,omitempty" gorm:"serializer:json;type:text"`
+	// RecurrenceEnd is the end of the last occurrence, or nil if the series
+	// does not end; it lets the repository find series overlapping a window
+	RecurrenceEnd *time.Time `json:"-" gorm:"index"`
+	// RecurrenceID is the start time of this occurrence when a recurring
+	// event has been expanded into its occurrences
+	RecurrenceID *time.Time `json:"recurrenceId,omitempty" gorm:"-"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// Location returns the time zone the event repeats in, falling back to UTC if
+// it is unset or unknown
+func (e CalendarEvent) Location() *time.Location {
+	return loadLocation(e.TimeZone)
+}
+
+// Meeting represents a meeting booked for one or more participants. Each
+// participant gets their own CalendarEvent linked back to the meeting.
+// Recurring meetings keep their rule, time zone and exceptions on the meeting
+// as well as on every participant's event.
+type Meeting struct {
+	ID             string          `json:"id" gorm:"primaryKey"`
+	Title          string          `json:"title"`
+	OrganizerID    string          `json:"organizerId" gorm:"index"`
+	StartTime      time.Time       `json:"startTime"`
+	EndTime        time.Time       `json:"endTime"`
+	Recurrence     string          `json:"recurrence,omitempty"`
+	TimeZone       string          `json:"timeZone,omitempty"`
+	Exceptions     []time.Time     `json:"exceptions,omitempty" gorm:"serializer:json;type:text"`
+	RecurrenceEnd  *time.Time      `json:"-"`
+	ParticipantIDs []string        `json:"participantIds" gorm:"-"`
+	ResourceIDs    []string        `json:"resourceIds,omitempty" gorm:"-"`
+	Events         []CalendarEvent `json:"-" gorm:"foreignKey:MeetingID"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	// BufferBeforeMinutes and BufferAfterMinutes are the buffers the meeting
+	// was booked with. Its participants' events keep them, so that later
+	// bookings leave that much time free around the meeting.
+	BufferBeforeMinutes int `json:"bufferBeforeMinutes,omitempty"`
+	BufferAfterMinutes  int `json:"bufferAfterMinutes,omitempty"`
+}
+
+// Location returns the time zone the meeting repeats in, falling back to UTC
+// if it is unset or unknown
+func (m Meeting) Location() *time.Location {
+	return loadLocation(m.TimeZone)
 }
 
 // ScheduleRequest represents the input for scheduling a new meeting
@@ -31,6 +247,71 @@ type ScheduleRequest struct {
 	DurationMinutes int       `json:"durationMinutes"`
 	TimeRange       TimeRange `json:"timeRange"`
 	Title           string    `json:"title,omitempty"`
+	OrganizerID     string    `json:"organizerId,omitempty"`
+	// OptionalParticipantIDs are invited if they are free; slots that more of
+	// them can attend are preferred
+	OptionalParticipantIDs []string `json:"optionalParticipantIds,omitempty"`
+	// MinAttendees is the quorum: the least number of participants, required
+	// and optional together, who must be able to attend. Required
+	// participants always have to attend.
+	MinAttendees int `json:"minAttendees,omitempty"`
+	// GranularityMinutes is the distance between candidate start times
+	// (5, 10, 15, 30 or 60); zero means DefaultGranularityMinutes
+	GranularityMinutes int `json:"granularityMinutes,omitempty"`
+	// Alignment is one of the Align* policies; empty means AlignNone
+	Alignment string `json:"alignment,omitempty"`
+	// Strategy names the scoring strategy that decides which free slot is
+	// best, e.g. "earliest" or "compact-day"; empty means the default
+	Strategy string `json:"strategy,omitempty"`
+	// Explain adds a breakdown of the chosen slot's score to the response
+	Explain bool `json:"explain,omitempty"`
+	// Recurrence is an RFC 5545 recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=MO",
+	// that makes the meeting a series starting at the chosen slot
+	Recurrence string `json:"recurrence,omitempty"`
+	// OccurrenceThreshold is the fraction (0-1] of occurrences that must be
+	// free for the required participants; zero means all of them. Busy
+	// occurrences are skipped. The first occurrence always has to be free.
+	OccurrenceThreshold float64 `json:"occurrenceThreshold,omitempty"`
+	// BufferBeforeMinutes and BufferAfterMinutes are the free time every
+	// participant needs before and after the meeting; participants whose own
+	// buffers are longer keep theirs
+	BufferBeforeMinutes int `json:"bufferBeforeMinutes,omitempty"`
+	BufferAfterMinutes  int `json:"bufferAfterMinutes,omitempty"`
+	// Resources lists the rooms and equipment the meeting needs; a free
+	// resource is picked for each requirement together with the slot
+	Resources []ResourceRequirement `json:"resources,omitempty"`
+}
+
+// DefaultGranularityMinutes is the distance between candidate start times
+// when a request does not specify one
+const DefaultGranularityMinutes = 15
+
+// Alignment policies for candidate start times. Clock times are read in the
+// location of the request's TimeRange.Start.
+const (
+	// AlignNone starts candidates at TimeRange.Start and every granularity after it
+	AlignNone = "none"
+	// AlignGranularity snaps candidates to clock multiples of the granularity, e.g. :00, :15, :30, :45
+	AlignGranularity = "granularity"
+	// AlignHalfHour snaps candidates to the full or half hour
+	AlignHalfHour = "half-hour"
+	// AlignHour snaps candidates to the full hour
+	AlignHour = "hour"
+)
+
+// RescheduleRequest represents the input for moving an existing meeting to a new time
+type RescheduleRequest struct {
+	DurationMinutes    int       `json:"durationMinutes,omitempty"`
+	TimeRange          TimeRange `json:"timeRange"`
+	GranularityMinutes int       `json:"granularityMinutes,omitempty"`
+	Alignment          string    `json:"alignment,omitempty"`
+	Strategy           string    `json:"strategy,omitempty"`
+	Explain            bool      `json:"explain,omitempty"`
+	// OccurrenceThreshold applies to recurring meetings as in ScheduleRequest
+	OccurrenceThreshold float64 `json:"occurrenceThreshold,omitempty"`
+	// BufferBeforeMinutes and BufferAfterMinutes apply as in ScheduleRequest
+	BufferBeforeMinutes int `json:"bufferBeforeMinutes,omitempty"`
+	BufferAfterMinutes  int `json:"bufferAfterMinutes,omitempty"`
 }
 
 // TimeRange represents a start and end time window
@@ -43,9 +324,106 @@ type TimeRange struct {
 type ScheduleResponse struct {
 	MeetingID      string    `json:"meetingId"`
 	Title          string    `json:"title"`
+	OrganizerID    string    `json:"organizerId"`
 	ParticipantIDs []string  `json:"participantIds"`
 	StartTime      time.Time `json:"startTime"`
 	EndTime        time.Time `json:"endTime"`
+	// UnavailableParticipantIDs are the optional participants who are busy
+	// and were not booked
+	UnavailableParticipantIDs []string `json:"unavailableParticipantIds,omitempty"`
+	Recurrence                string   `json:"recurrence,omitempty"`
+	// SkippedOccurrences are the occurrences of a series that were left out
+	// because a required participant is busy
+	SkippedOccurrences []time.Time `json:"skippedOccurrences,omitempty"`
+	// ResourceIDs are the resources booked for the meeting, one for each
+	// requirement of the request in the same order
+	ResourceIDs []string `json:"resourceIds,omitempty"`
+	// Explanation is only set when the request asked for it
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+}
+
+// AvailabilityRequest asks for the best candidate slots for a meeting without booking any of them
+type AvailabilityRequest struct {
+	ScheduleRequest
+	Limit int `json:"limit,omitempty"`
+}
+
+// AvailabilityResponse lists candidate slots ordered from best to worst
+type AvailabilityResponse struct {
+	Slots []SlotSuggestion `json:"slots"`
+}
+
+// FreeBusyRequest asks when the participants are busy and when all of them
+// are free within a time range, without booking anything
+type FreeBusyRequest struct {
+	ParticipantIDs []string  `json:"participantIds"`
+	TimeRange      TimeRange `json:"timeRange"`
+}
+
+// FreeBusyResponse reports each participant's busy intervals, keyed by user
+// ID, and the intervals in which every participant is free. Event titles are
+// deliberately left out.
+type FreeBusyResponse struct {
+	Busy map[string][]Interval `json:"busy"`
+	Free []Interval            `json:"free"`
+}
+
+// Interval is a half-open time interval [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SlotSuggestion is a candidate meeting slot together with its score
+type SlotSuggestion struct {
+	StartTime                 time.Time        `json:"startTime"`
+	EndTime                   time.Time        `json:"endTime"`
+	Score                     float64          `json:"score"`
+	Breakdown                 []CriterionScore `json:"breakdown"`
+	UnavailableParticipantIDs []string         `json:"unavailableParticipantIds,omitempty"`
+	SkippedOccurrences        []time.Time      `json:"skippedOccurrences,omitempty"`
+	ResourceIDs               []string         `json:"resourceIds,omitempty"`
+}
+
+// CriterionScore is the contribution of a single scoring criterion to a slot's
+// score. Score is the average of the participants' individual scores.
+type CriterionScore struct {
+	Name         string             `json:"name"`
+	Score        float64            `json:"score"`
+	Weight       float64            `json:"weight"`
+	Weighted     float64            `json:"weighted"`
+	Participants []ParticipantScore `json:"participants,omitempty"`
+}
+
+// ParticipantScore is how well a slot suits a single participant under one criterion
+type ParticipantScore struct {
+	UserID string  `json:"userId"`
+	Score  float64 `json:"score"`
+}
+
+// ScoreExplanation tells why a slot was chosen: its total score and how every
+// criterion contributed to it
+type ScoreExplanation struct {
+	Score     float64          `json:"score"`
+	Strategy  string           `json:"strategy"`
+	Breakdown []CriterionScore `json:"breakdown"`
+}
+
+// ImportResult summarizes an iCalendar import
+type ImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	// Removed counts the previously imported events deleted because the file
+	// marks them as cancelled or as not blocking time
+	Removed int `json:"removed"`
+	// Skipped lists the events that were not imported and why
+	Skipped []SkippedEvent `json:"skipped,omitempty"`
+}
+
+// SkippedEvent is an event left out of an iCalendar import
+type SkippedEvent struct {
+	UID    string `json:"uid"`
+	Reason string `json:"reason"`
 }
 
 // NewUser creates a new user with the given name
@@ -58,6 +436,17 @@ func NewUser(name string) *User {
 	}
 }
 
+// NewResource creates a new resource with the given name and kind
+func NewResource(name, kind string) *Resource {
+	return &Resource{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
 // NewCalendarEvent creates a new calendar event
 func NewCalendarEvent(title string, startTime, endTime time.Time, userID string) *CalendarEvent {
 	return &CalendarEvent{
@@ -70,3 +459,39 @@ func NewCalendarEvent(title string, startTime, endTime time.Time, userID string)
 		UpdatedAt: time.Now(),
 	}
 }
+
+// NewMeeting creates a new meeting with the given ID
+func NewMeeting(id, title, organizerID string, startTime, endTime time.Time, participantIDs []string) *Meeting {
+	return &Meeting{
+		ID:             id,
+		Title:          title,
+		OrganizerID:    organizerID,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		ParticipantIDs: participantIDs,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+// NewMeetingEvent creates the calendar event that places a meeting on a participant's calendar
+func NewMeetingEvent(meeting *Meeting, userID string) *CalendarEvent {
+	event := NewCalendarEvent(meeting.Title, meeting.StartTime, meeting.EndTime, userID)
+	event.MeetingID = &meeting.ID
+	event.Recurrence = meeting.Recurrence
+	event.TimeZone = meeting.TimeZone
+	event.Exceptions = meeting.Exceptions
+	event.RecurrenceEnd = meeting.RecurrenceEnd
+	event.BufferBeforeMinutes = meeting.BufferBeforeMinutes
+	event.BufferAfterMinutes = meeting.BufferAfterMinutes
+	return event
+}
+
+// NewResourceEvent creates the calendar event that books a resource for a meeting
+func NewResourceEvent(meeting *Meeting, resourceID string) *CalendarEvent {
+	event := NewMeetingEvent(meeting, "")
+	event.ResourceID = &resourceID
+	// Buffers are the participants' free time; resources don't need them
+	event.BufferBeforeMinutes, event.BufferAfterMinutes = 0, 0
+	return event
+}
@@ -11,39 +11,272 @@ import (
 
 // Endpoints holds all Go kit endpoints for the scheduler service
 type Endpoints struct {
-	Schedule        endpoint.Endpoint
-	GetUserCalendar endpoint.Endpoint
+	Schedule            endpoint.Endpoint
+	GetUserCalendar     endpoint.Endpoint
+	ImportCalendar      endpoint.Endpoint
+	GetMeeting          endpoint.Endpoint
+	CancelMeeting       endpoint.Endpoint
+	CancelOccurrence    endpoint.Endpoint
+	RescheduleMeeting   endpoint.Endpoint
+	FindAvailability    endpoint.Endpoint
+	FreeBusy            endpoint.Endpoint
+	CreateUser          endpoint.Endpoint
+	ListUsers           endpoint.Endpoint
+	GetUser             endpoint.Endpoint
+	UpdateUser          endpoint.Endpoint
+	DeleteUser          endpoint.Endpoint
+	CreateEvent         endpoint.Endpoint
+	UpdateEvent         endpoint.Endpoint
+	DeleteEvent         endpoint.Endpoint
+	CreateResource      endpoint.Endpoint
+	ListResources       endpoint.Endpoint
+	GetResource         endpoint.Endpoint
+	GetResourceCalendar endpoint.Endpoint
 }
 
 // MakeEndpoints creates the service endpoints
 func MakeEndpoints(s service.SchedulerService) Endpoints {
 	return Endpoints{
-		Schedule:        makeScheduleEndpoint(s),
-		GetUserCalendar: makeGetUserCalendarEndpoint(s),
+		Schedule:            makeScheduleEndpoint(s),
+		GetUserCalendar:     makeGetUserCalendarEndpoint(s),
+		ImportCalendar:      makeImportCalendarEndpoint(s),
+		GetMeeting:          makeGetMeetingEndpoint(s),
+		CancelMeeting:       makeCancelMeetingEndpoint(s),
+		CancelOccurrence:    makeCancelOccurrenceEndpoint(s),
+		RescheduleMeeting:   makeRescheduleMeetingEndpoint(s),
+		FindAvailability:    makeFindAvailabilityEndpoint(s),
+		FreeBusy:            makeFreeBusyEndpoint(s),
+		CreateUser:          makeCreateUserEndpoint(s),
+		ListUsers:           makeListUsersEndpoint(s),
+		GetUser:             makeGetUserEndpoint(s),
+		UpdateUser:          makeUpdateUserEndpoint(s),
+		DeleteUser:          makeDeleteUserEndpoint(s),
+		CreateEvent:         makeCreateEventEndpoint(s),
+		UpdateEvent:         makeUpdateEventEndpoint(s),
+		DeleteEvent:         makeDeleteEventEndpoint(s),
+		CreateResource:      makeCreateResourceEndpoint(s),
+		ListResources:       makeListResourcesEndpoint(s),
+		GetResource:         makeGetResourceEndpoint(s),
+		GetResourceCalendar: makeGetResourceCalendarEndpoint(s),
 	}
 }
 
 func makeScheduleEndpoint(s service.SchedulerService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(domain.ScheduleRequest)
-		resp, err := s.Schedule(ctx, req)
-		// If the error is ErrInvalidRequest, check for a more specific error from validation
-		if err != nil && err.Error() != service.ErrInvalidRequest.Error() {
-			return nil, err
-		}
-		return resp, err
+		return s.Schedule(ctx, req)
 	}
 }
 
 func makeGetUserCalendarEndpoint(s service.SchedulerService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(GetUserCalendarRequest)
+		if req.ICalendar {
+			events, err := s.ExportCalendar(ctx, req.UserID, req.Start, req.End)
+			if err != nil {
+				return nil, err
+			}
+			return ICalendarResponse{Events: events}, nil
+		}
 		return s.GetUserCalendar(ctx, req.UserID, req.Start, req.End)
 	}
 }
 
+func makeImportCalendarEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ImportCalendarRequest)
+		return s.ImportCalendar(ctx, req.UserID, req.Data)
+	}
+}
+
+func makeGetMeetingEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetMeetingRequest)
+		return s.GetMeeting(ctx, req.MeetingID)
+	}
+}
+
+func makeCancelMeetingEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CancelMeetingRequest)
+		return nil, s.CancelMeeting(ctx, req.MeetingID)
+	}
+}
+
+func makeCancelOccurrenceEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CancelOccurrenceRequest)
+		return nil, s.CancelOccurrence(ctx, req.MeetingID, req.Occurrence)
+	}
+}
+
+func makeRescheduleMeetingEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RescheduleMeetingRequest)
+		return s.RescheduleMeeting(ctx, req.MeetingID, req.RescheduleRequest)
+	}
+}
+
+func makeFindAvailabilityEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(domain.AvailabilityRequest)
+		return s.FindAvailability(ctx, req)
+	}
+}
+
+func makeFreeBusyEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(domain.FreeBusyRequest)
+		return s.FreeBusy(ctx, req)
+	}
+}
+
+func makeCreateUserEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(domain.CreateUserRequest)
+		return s.CreateUser(ctx, req)
+	}
+}
+
+func makeListUsersEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.ListUsers(ctx)
+	}
+}
+
+func makeGetUserEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UserRequest)
+		return s.GetUser(ctx, req.UserID)
+	}
+}
+
+func makeUpdateUserEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateUserRequest)
+		return s.UpdateUser(ctx, req.UserID, req.UpdateUserRequest)
+	}
+}
+
+func makeDeleteUserEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UserRequest)
+		return nil, s.DeleteUser(ctx, req.UserID)
+	}
+}
+
+func makeCreateEventEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(EventRequest)
+		return s.CreateEvent(ctx, req.UserID, req.EventRequest)
+	}
+}
+
+func makeUpdateEventEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(EventRequest)
+		return s.UpdateEvent(ctx, req.UserID, req.EventID, req.EventRequest)
+	}
+}
+
+func makeDeleteEventEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(EventRequest)
+		return nil, s.DeleteEvent(ctx, req.UserID, req.EventID)
+	}
+}
+
+func makeCreateResourceEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(domain.CreateResourceRequest)
+		return s.CreateResource(ctx, req)
+	}
+}
+
+func makeListResourcesEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.ListResources(ctx)
+	}
+}
+
+func makeGetResourceEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ResourceRequest)
+		return s.GetResource(ctx, req.ResourceID)
+	}
+}
+
+func makeGetResourceCalendarEndpoint(s service.SchedulerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetResourceCalendarRequest)
+		return s.GetResourceCalendar(ctx, req.ResourceID, req.Start, req.End)
+	}
+}
+
+// GetUserCalendarRequest asks for a user's calendar as JSON or, if ICalendar
+// is set, as an iCalendar file
 type GetUserCalendarRequest struct {
+	UserID    string
+	Start     time.Time
+	End       time.Time
+	ICalendar bool
+}
+
+// ICalendarResponse holds the events to write as an iCalendar file
+type ICalendarResponse struct {
+	Events []domain.CalendarEvent
+}
+
+// ImportCalendarRequest holds an iCalendar file to import into a user's calendar
+type ImportCalendarRequest struct {
 	UserID string
-	Start  time.Time
-	End    time.Time
+	Data   []byte
+}
+
+type GetMeetingRequest struct {
+	MeetingID string
+}
+
+type CancelMeetingRequest struct {
+	MeetingID string
+}
+
+// CancelOccurrenceRequest identifies one occurrence of a recurring meeting by its start time
+type CancelOccurrenceRequest struct {
+	MeetingID  string
+	Occurrence time.Time
+}
+
+type RescheduleMeetingRequest struct {
+	MeetingID string
+	domain.RescheduleRequest
+}
+
+// UserRequest identifies a single user for get and delete operations
+type UserRequest struct {
+	UserID string
+}
+
+type UpdateUserRequest struct {
+	UserID string
+	domain.UpdateUserRequest
+}
+
+// ResourceRequest identifies a single resource
+type ResourceRequest struct {
+	ResourceID string
+}
+
+// GetResourceCalendarRequest asks for the bookings of a resource within a window
+type GetResourceCalendarRequest struct {
+	ResourceID string
+	Start      time.Time
+	End        time.Time
+}
+
+// EventRequest addresses an event on a user's calendar; EventID is empty when creating one
+type EventRequest struct {
+	UserID  string
+	EventID string
+	domain.EventRequest
 }
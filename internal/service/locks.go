@@ -0,0 +1,64 @@
+package service
+
+import (
+	"sort"
+	"sync"
+)
+
+// participantLocks hands out one mutex per participant so that bookings for
+// overlapping sets of participants are serialized while unrelated bookings
+// proceed in parallel
+type participantLocks struct {
+	mu    sync.Mutex
+	locks map[string]*participantLock
+}
+
+type participantLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newParticipantLocks() *participantLocks {
+	return &participantLocks{
+		locks: make(map[string]*participantLock),
+	}
+}
+
+// Lock acquires the locks of all given participants and returns a function
+// that releases them. Locks are always taken in sorted order to avoid
+// deadlocks between requests that share several participants.
+func (p *participantLocks) Lock(ids []string) func() {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+
+	acquired := make([]string, 0, len(sorted))
+	for i, id := range sorted {
+		if i > 0 && sorted[i-1] == id {
+			continue
+		}
+		p.mu.Lock()
+		lock, ok := p.locks[id]
+		if !ok {
+			lock = &participantLock{}
+			p.locks[id] = lock
+		}
+		lock.refs++
+		p.mu.Unlock()
+
+		lock.Lock()
+		acquired = append(acquired, id)
+	}
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i := len(acquired) - 1; i >= 0; i-- {
+			lock := p.locks[acquired[i]]
+			lock.Unlock()
+			lock.refs--
+			if lock.refs == 0 {
+				delete(p.locks, acquired[i])
+			}
+		}
+	}
+}
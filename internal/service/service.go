@@ -1,44 +1,220 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/meeting-scheduler/internal/domain"
 	"github.com/meeting-scheduler/pkg/algorithm"
+	"github.com/meeting-scheduler/pkg/ical"
+	"github.com/meeting-scheduler/pkg/recurrence"
+	"github.com/meeting-scheduler/pkg/repository"
 )
 
 var (
-	ErrInvalidRequest  = errors.New("invalid request parameters")
-	ErrNoAvailableSlot = errors.New("no available time slot found for all participants")
-	ErrUserNotFound    = errors.New("user not found")
-	ErrInternalError   = errors.New("internal server error")
+	ErrInvalidRequest     = errors.New("invalid request parameters")
+	ErrNoAvailableSlot    = errors.New("no available time slot found for all participants")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrMeetingNotFound    = errors.New("meeting not found")
+	ErrOccurrenceNotFound = errors.New("meeting has no such occurrence")
+	ErrSlotConflict       = errors.New("time slot was booked by a concurrent request, please retry")
+	ErrEventNotFound      = errors.New("event not found")
+	ErrResourceNotFound   = errors.New("resource not found")
+	ErrNoMatchingResource = errors.New("no resource meets the requirements")
+	ErrEventConflict      = errors.New("event overlaps an existing event")
+	ErrMeetingEvent       = errors.New("meeting events can only be changed through the meeting endpoints")
+	ErrInternalError      = errors.New("internal server error")
+	ErrUnavailable        = errors.New("storage is temporarily unavailable, please retry")
 )
 
+// MissingUsersError lists the requested users that do not exist. It matches
+// ErrUserNotFound with errors.Is.
+type MissingUsersError struct {
+	IDs []string
+}
+
+func (e *MissingUsersError) Error() string {
+	return fmt.Sprintf("users not found: %s", strings.Join(e.IDs, ", "))
+}
+
+func (e *MissingUsersError) Is(target error) bool {
+	return target == ErrUserNotFound
+}
+
+// Validation error codes, telling clients what is wrong with a field
+const (
+	CodeRequired   = "required"
+	CodeInvalid    = "invalid"
+	CodeDuplicate  = "duplicate"
+	CodeOutOfRange = "out_of_range"
+)
+
+// ValidationError reports a request field that failed validation. Field is
+// the field's JSON name, or empty if the request as a whole is invalid.
+// ValidationErrors match ErrInvalidRequest with errors.Is.
+type ValidationError struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// invalid returns a ValidationError for field with a formatted message
+func invalid(field, code, format string, args ...interface{}) error {
+	return &ValidationError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
 // SchedulerService defines the interface for our meeting scheduler
 type SchedulerService interface {
 	Schedule(ctx context.Context, req domain.ScheduleRequest) (*domain.ScheduleResponse, error)
 
 	GetUserCalendar(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error)
+
+	ExportCalendar(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error)
+
+	ImportCalendar(ctx context.Context, userID string, data []byte) (*domain.ImportResult, error)
+
+	GetMeeting(ctx context.Context, meetingID string) (*domain.Meeting, error)
+
+	CancelMeeting(ctx context.Context, meetingID string) error
+
+	CancelOccurrence(ctx context.Context, meetingID string, occurrence time.Time) error
+
+	RescheduleMeeting(ctx context.Context, meetingID string, req domain.RescheduleRequest) (*domain.ScheduleResponse, error)
+
+	FindAvailability(ctx context.Context, req domain.AvailabilityRequest) (*domain.AvailabilityResponse, error)
+
+	FreeBusy(ctx context.Context, req domain.FreeBusyRequest) (*domain.FreeBusyResponse, error)
+
+	CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error)
+
+	ListUsers(ctx context.Context) ([]domain.User, error)
+
+	GetUser(ctx context.Context, userID string) (*domain.User, error)
+
+	UpdateUser(ctx context.Context, userID string, req domain.UpdateUserRequest) (*domain.User, error)
+
+	DeleteUser(ctx context.Context, userID string) error
+
+	CreateEvent(ctx context.Context, userID string, req domain.EventRequest) (*domain.CalendarEvent, error)
+
+	UpdateEvent(ctx context.Context, userID, eventID string, req domain.EventRequest) (*domain.CalendarEvent, error)
+
+	DeleteEvent(ctx context.Context, userID, eventID string) error
+
+	CreateResource(ctx context.Context, req domain.CreateResourceRequest) (*domain.Resource, error)
+
+	ListResources(ctx context.Context) ([]domain.Resource, error)
+
+	GetResource(ctx context.Context, resourceID string) (*domain.Resource, error)
+
+	GetResourceCalendar(ctx context.Context, resourceID string, start, end time.Time) ([]domain.CalendarEvent, error)
 }
 
-// Repository defines the interface for data persistence
+// Repository defines the interface for data persistence. Lookups of a
+// missing record fail with repository.ErrNotFound.
 type Repository interface {
 	GetUser(ctx context.Context, id string) (*domain.User, error)
+	// GetUsers returns the users with the given IDs that exist, in no
+	// particular order
+	GetUsers(ctx context.Context, ids []string) ([]domain.User, error)
+	ListUsers(ctx context.Context) ([]domain.User, error)
+	CreateUser(ctx context.Context, user *domain.User) error
+	UpdateUser(ctx context.Context, user *domain.User) error
+	// DeleteUser removes a user and all of their events. The user is dropped
+	// from the meetings they attend; meetings left without participants are
+	// deleted and meetings they organized pass to a remaining participant.
+	DeleteUser(ctx context.Context, id string) error
+	// GetUserEvents returns the user's events that overlap [start, end),
+	// including recurring events with an occurrence that might; callers expand
+	// those with recurrence.ExpandAll
 	GetUserEvents(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error)
+	// GetEventsForUsers does what GetUserEvents does for several users at
+	// once, returning the events keyed by user ID
+	GetEventsForUsers(ctx context.Context, userIDs []string, start, end time.Time) (map[string][]domain.CalendarEvent, error)
+	GetEvent(ctx context.Context, id string) (*domain.CalendarEvent, error)
 	CreateEvent(ctx context.Context, event *domain.CalendarEvent) error
+	UpdateEvent(ctx context.Context, event *domain.CalendarEvent) error
+	DeleteEvent(ctx context.Context, id string) error
+	// ImportEvents creates or, if the user already has an event with the
+	// same UID, replaces the given events, and deletes the user's events with
+	// one of the removed UIDs; it returns how many were created and deleted
+	ImportEvents(ctx context.Context, userID string, events []*domain.CalendarEvent, removedUIDs []string) (created, removed int, err error)
+	CreateMeetingWithEvents(ctx context.Context, meeting *domain.Meeting, events []*domain.CalendarEvent) error
+	GetMeeting(ctx context.Context, id string) (*domain.Meeting, error)
+	DeleteMeeting(ctx context.Context, id string) error
+	// UpdateMeetingTime moves a meeting and its events to the meeting's
+	// StartTime and EndTime, replacing the exceptions of a recurring meeting
+	// and the buffers of the participants' events
+	UpdateMeetingTime(ctx context.Context, meeting *domain.Meeting) error
+	// SetMeetingExceptions replaces the exceptions of a recurring meeting and its events
+	SetMeetingExceptions(ctx context.Context, id string, exceptions []time.Time) error
+	CreateResource(ctx context.Context, resource *domain.Resource) error
+	GetResource(ctx context.Context, id string) (*domain.Resource, error)
+	ListResources(ctx context.Context) ([]domain.Resource, error)
+	// GetEventsForResources returns the events booking the resources that
+	// overlap [start, end), keyed by resource ID, with recurring events
+	// unexpanded as in GetUserEvents
+	GetEventsForResources(ctx context.Context, resourceIDs []string, start, end time.Time) (map[string][]domain.CalendarEvent, error)
 }
 
+// Default window of ExportCalendar, relative to now, for calendar clients that
+// subscribe without asking for a particular window
+const (
+	defaultExportPast   = 30 * 24 * time.Hour
+	defaultExportFuture = recurrence.Horizon
+)
+
+// Bounds on the number of candidate slots returned by FindAvailability
+const (
+	defaultAvailabilityLimit = 5
+	maxAvailabilityLimit     = 50
+)
+
+// maxBookingAttempts bounds how often a booking is recomputed after the
+// repository reports that a concurrent request took the chosen slot
+const maxBookingAttempts = 3
+
 type service struct {
-	repo Repository
+	repo    Repository
+	locks   *participantLocks
+	scorers *algorithm.Registry
+}
+
+// Option customizes the service created by NewService
+type Option func(*service)
+
+// WithScorers selects scoring strategies from the given registry instead of
+// the built-in strategies
+func WithScorers(registry *algorithm.Registry) Option {
+	return func(s *service) {
+		s.scorers = registry
+	}
 }
 
-func NewService(repo Repository) SchedulerService {
-	return &service{
-		repo: repo,
+func NewService(repo Repository, opts ...Option) SchedulerService {
+	s := &service{
+		repo:    repo,
+		locks:   newParticipantLocks(),
+		scorers: algorithm.NewRegistry(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Schedule implements the core scheduling logic
@@ -47,122 +223,1224 @@ func (s *service) Schedule(ctx context.Context, req domain.ScheduleRequest) (*do
 		return nil, err
 	}
 
-	for _, userID := range req.ParticipantIDs {
-		if _, err := s.repo.GetUser(ctx, userID); err != nil {
-			return nil, ErrUserNotFound
-		}
+	scorer, err := s.scorer(req.Strategy)
+	if err != nil {
+		return nil, err
 	}
 
-	allEvents := make(map[string][]domain.CalendarEvent)
-	for _, userID := range req.ParticipantIDs {
-		events, err := s.repo.GetUserEvents(ctx, userID, req.TimeRange.Start, req.TimeRange.End)
-		if err != nil {
-			return nil, ErrInternalError
-		}
-		allEvents[userID] = events
+	invited := invitedParticipants(req)
+	participants, err := s.loadUsers(ctx, invited)
+	if err != nil {
+		return nil, err
 	}
-
-	slot, err := algorithm.FindOptimalSlot(req, allEvents)
+	pools, err := s.resourcePools(ctx, req.Resources)
 	if err != nil {
-		return nil, ErrInternalError
+		return nil, err
 	}
-	if slot == nil {
-		return nil, ErrNoAvailableSlot
+
+	organizer, err := s.organizer(ctx, req, participants)
+	if err != nil {
+		return nil, err
 	}
+	organizerID := organizer.ID
 
-	meetingID := generateMeetingID()
 	meetingTitle := req.Title
 	if meetingTitle == "" {
 		meetingTitle = "New Meeting"
 	}
-	for _, userID := range req.ParticipantIDs {
-		event := domain.NewCalendarEvent(
+
+	// Serialize bookings that share a participant or a candidate resource
+	// within this instance; the repository re-checks for conflicts to cover
+	// other instances.
+	unlock := s.locks.Lock(append(append([]string(nil), invited...), poolResources(pools)...))
+	defer unlock()
+
+	window, seriesOpts := searchWindow(req, organizer.Location())
+	opts := append([]algorithm.Option{algorithm.WithParticipants(participants...), algorithm.WithScorer(scorer), algorithm.WithResources(pools...)}, seriesOpts...)
+
+	for attempt := 0; attempt < maxBookingAttempts; attempt++ {
+		allEvents, err := s.participantEvents(ctx, invited, window, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addResourceEvents(ctx, allEvents, poolResources(pools), window, ""); err != nil {
+			return nil, err
+		}
+
+		slot, err := algorithm.FindOptimalSlot(req, allEvents, opts...)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		if slot == nil {
+			return nil, ErrNoAvailableSlot
+		}
+
+		attendeeIDs := attendees(invited, slot.Unavailable)
+		meeting := domain.NewMeeting(
+			generateMeetingID(),
 			meetingTitle,
+			organizerID,
 			slot.Start,
 			slot.End,
-			userID,
+			attendeeIDs,
 		)
-		if err := s.repo.CreateEvent(ctx, event); err != nil {
-			return nil, ErrInternalError
+		setSeries(meeting, req.Recurrence, organizer.TimeZone, slot.Skipped)
+		meeting.ResourceIDs = slot.Resources
+		meeting.BufferBeforeMinutes = req.BufferBeforeMinutes
+		meeting.BufferAfterMinutes = req.BufferAfterMinutes
+		events := make([]*domain.CalendarEvent, 0, len(attendeeIDs)+len(slot.Resources))
+		for _, userID := range attendeeIDs {
+			events = append(events, domain.NewMeetingEvent(meeting, userID))
+		}
+		for _, resourceID := range slot.Resources {
+			events = append(events, domain.NewResourceEvent(meeting, resourceID))
 		}
+		err = s.repo.CreateMeetingWithEvents(ctx, meeting, events)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, storageError(err, nil)
+		}
+
+		resp := &domain.ScheduleResponse{
+			MeetingID:                 meeting.ID,
+			Title:                     meetingTitle,
+			OrganizerID:               organizerID,
+			ParticipantIDs:            attendeeIDs,
+			StartTime:                 slot.Start,
+			EndTime:                   slot.End,
+			UnavailableParticipantIDs: slot.Unavailable,
+			Recurrence:                meeting.Recurrence,
+			SkippedOccurrences:        meeting.Exceptions,
+			ResourceIDs:               slot.Resources,
+		}
+		if req.Explain {
+			resp.Explanation = explain(slot, req.Strategy)
+		}
+		return resp, nil
 	}
 
-	return &domain.ScheduleResponse{
-		MeetingID:      meetingID,
-		Title:          meetingTitle,
-		ParticipantIDs: req.ParticipantIDs,
-		StartTime:      slot.Start,
-		EndTime:        slot.End,
-	}, nil
+	return nil, ErrSlotConflict
 }
 
 func (s *service) GetUserCalendar(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error) {
 	if _, err := s.repo.GetUser(ctx, userID); err != nil {
-		return nil, ErrUserNotFound
+		return nil, storageError(err, ErrUserNotFound)
 	}
 
 	events, err := s.repo.GetUserEvents(ctx, userID, start, end)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	events, err = recurrence.ExpandAll(events, start, end)
 	if err != nil {
 		return nil, ErrInternalError
 	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
 	if len(events) == 0 {
 		return nil, errors.New("no meetings found for the specified user and time window")
 	}
 	return events, nil
 }
 
+// ExportCalendar returns the user's events with an occurrence in the window,
+// without expanding recurring events, for export to other calendar clients.
+// A zero start or end selects a window from 30 days ago to a year ahead.
+func (s *service) ExportCalendar(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error) {
+	if _, err := s.repo.GetUser(ctx, userID); err != nil {
+		return nil, storageError(err, ErrUserNotFound)
+	}
+
+	now := time.Now()
+	if start.IsZero() {
+		start = now.Add(-defaultExportPast)
+	}
+	if end.IsZero() {
+		end = now.Add(defaultExportFuture)
+	}
+	if !start.Before(end) {
+		return nil, invalid("end", CodeInvalid, "start time must be before end time")
+	}
+
+	events, err := s.repo.GetUserEvents(ctx, userID, start, end)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	exported := make([]domain.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		occurrences, err := recurrence.Expand(event, start, end)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		if len(occurrences) > 0 {
+			exported = append(exported, event)
+		}
+	}
+	sort.SliceStable(exported, func(i, j int) bool {
+		return exported[i].StartTime.Before(exported[j].StartTime)
+	})
+	return exported, nil
+}
+
+// ImportCalendar adds the events of an iCalendar file to the user's calendar
+// so that scheduling sees the time they block. Events are matched by UID, so
+// importing the same file again updates the events instead of duplicating
+// them. Unlike CreateEvent, imported events may overlap existing ones.
+// Events that do not block time, or whose time zone or recurrence rule is not
+// supported, are skipped and reported in the result.
+func (s *service) ImportCalendar(ctx context.Context, userID string, data []byte) (*domain.ImportResult, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, storageError(err, ErrUserNotFound)
+	}
+	parsed, err := ical.Parse(bytes.NewReader(data), user.Location())
+	if err != nil {
+		return nil, invalid("", CodeInvalid, "invalid iCalendar data: %v", err)
+	}
+
+	result := &domain.ImportResult{}
+	skip := func(uid, reason string) {
+		result.Skipped = append(result.Skipped, domain.SkippedEvent{UID: uid, Reason: reason})
+	}
+
+	// Overridden occurrences become exceptions of their series and are
+	// imported as events of their own
+	overridden := make(map[string][]time.Time)
+	for _, event := range parsed {
+		if event.RecurrenceID != nil && event.Err == nil {
+			overridden[event.UID] = append(overridden[event.UID], *event.RecurrenceID)
+		}
+	}
+
+	var events []*domain.CalendarEvent
+	index := make(map[string]int)
+	// removed holds the UIDs of events that no longer block time, whose
+	// earlier imports are deleted
+	removed := make(map[string]bool)
+	for _, event := range parsed {
+		uid := event.UID
+		if event.RecurrenceID != nil {
+			uid = event.UID + "/" + event.RecurrenceID.UTC().Format("20060102T150405Z")
+		}
+
+		switch {
+		case ical.IsGenerated(event.UID):
+			skip(event.UID, "event was exported by this scheduler")
+			continue
+		case event.Err != nil:
+			skip(event.UID, event.Err.Error())
+			continue
+		case event.Cancelled:
+			skip(event.UID, "event is cancelled")
+			removed[uid] = true
+			continue
+		case event.Transparent:
+			skip(event.UID, "event does not block time")
+			removed[uid] = true
+			continue
+		case !event.Start.Before(event.End):
+			skip(event.UID, "event has no duration")
+			continue
+		}
+
+		imported := domain.NewCalendarEvent(event.Summary, event.Start, event.End, userID)
+		imported.UID = uid
+		if imported.Title == "" {
+			imported.Title = "Busy"
+		}
+		if event.RecurrenceID == nil && event.Recurrence != "" {
+			rule, err := recurrence.Parse(event.Recurrence)
+			if err != nil {
+				skip(event.UID, err.Error())
+				continue
+			}
+			imported.Recurrence = rule.String()
+			// The series repeats in the zone its start is given in
+			if loc := event.Start.Location(); loc != time.UTC {
+				imported.TimeZone = loc.String()
+			}
+			imported.Exceptions = append(append([]time.Time(nil), event.Exceptions...), overridden[event.UID]...)
+			imported.RecurrenceEnd = seriesEnd(rule, event.Start, event.End)
+		}
+
+		// A UID repeated within the file replaces the earlier event
+		delete(removed, uid)
+		if i, ok := index[uid]; ok {
+			events[i] = imported
+			continue
+		}
+		index[uid] = len(events)
+		events = append(events, imported)
+	}
+
+	// An event cancelled later in the file is not imported at all
+	kept := events[:0]
+	for _, event := range events {
+		if !removed[event.UID] {
+			kept = append(kept, event)
+		}
+	}
+	events = kept
+	removedUIDs := make([]string, 0, len(removed))
+	for uid := range removed {
+		removedUIDs = append(removedUIDs, uid)
+	}
+	sort.Strings(removedUIDs)
+
+	if len(events) > 0 || len(removedUIDs) > 0 {
+		created, deleted, err := s.repo.ImportEvents(ctx, userID, events, removedUIDs)
+		if err != nil {
+			return nil, storageError(err, nil)
+		}
+		result.Created = created
+		result.Updated = len(events) - created
+		result.Removed = deleted
+	}
+	return result, nil
+}
+
+func (s *service) GetMeeting(ctx context.Context, meetingID string) (*domain.Meeting, error) {
+	meeting, err := s.repo.GetMeeting(ctx, meetingID)
+	if err != nil {
+		return nil, storageError(err, ErrMeetingNotFound)
+	}
+	return meeting, nil
+}
+
+// CancelMeeting removes a meeting from every participant's calendar
+func (s *service) CancelMeeting(ctx context.Context, meetingID string) error {
+	if _, err := s.repo.GetMeeting(ctx, meetingID); err != nil {
+		return storageError(err, ErrMeetingNotFound)
+	}
+	if err := s.repo.DeleteMeeting(ctx, meetingID); err != nil {
+		return storageError(err, nil)
+	}
+	return nil
+}
+
+// CancelOccurrence removes a single occurrence of a recurring meeting from
+// every participant's calendar by recording it as an exception
+func (s *service) CancelOccurrence(ctx context.Context, meetingID string, occurrence time.Time) error {
+	meeting, err := s.repo.GetMeeting(ctx, meetingID)
+	if err != nil {
+		return storageError(err, ErrMeetingNotFound)
+	}
+	if meeting.Recurrence == "" {
+		return ErrOccurrenceNotFound
+	}
+	rule, err := recurrence.Parse(meeting.Recurrence)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	occurrences := rule.Between(meeting.StartTime.In(meeting.Location()), occurrence, occurrence.Add(time.Nanosecond))
+	if len(occurrences) == 0 || !occurrences[0].Equal(occurrence) {
+		return ErrOccurrenceNotFound
+	}
+	for _, exception := range meeting.Exceptions {
+		if exception.Equal(occurrence) {
+			return ErrOccurrenceNotFound
+		}
+	}
+
+	exceptions := append(append([]time.Time(nil), meeting.Exceptions...), occurrence)
+	if err := s.repo.SetMeetingExceptions(ctx, meetingID, exceptions); err != nil {
+		return storageError(err, nil)
+	}
+	return nil
+}
+
+// RescheduleMeeting finds a new slot for an existing meeting within the given
+// time range and moves every participant's event to it. The meeting's own
+// events are ignored when checking availability so it can shift by less than
+// its own duration. A recurring meeting moves as a whole series, with its
+// exceptions recomputed for the new time.
+func (s *service) RescheduleMeeting(ctx context.Context, meetingID string, req domain.RescheduleRequest) (*domain.ScheduleResponse, error) {
+	meeting, err := s.repo.GetMeeting(ctx, meetingID)
+	if err != nil {
+		return nil, storageError(err, ErrMeetingNotFound)
+	}
+
+	durationMinutes := req.DurationMinutes
+	if durationMinutes == 0 {
+		durationMinutes = int(meeting.EndTime.Sub(meeting.StartTime).Minutes())
+	}
+	scheduleReq := domain.ScheduleRequest{
+		ParticipantIDs:      meeting.ParticipantIDs,
+		DurationMinutes:     durationMinutes,
+		TimeRange:           req.TimeRange,
+		Title:               meeting.Title,
+		OrganizerID:         meeting.OrganizerID,
+		GranularityMinutes:  req.GranularityMinutes,
+		Alignment:           req.Alignment,
+		Strategy:            req.Strategy,
+		Recurrence:          meeting.Recurrence,
+		OccurrenceThreshold: req.OccurrenceThreshold,
+		BufferBeforeMinutes: req.BufferBeforeMinutes,
+		BufferAfterMinutes:  req.BufferAfterMinutes,
+	}
+	if err := validateScheduleRequest(scheduleReq); err != nil {
+		return nil, err
+	}
+	scorer, err := s.scorer(req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := s.loadUsers(ctx, meeting.ParticipantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := s.locks.Lock(append(append([]string(nil), meeting.ParticipantIDs...), meeting.ResourceIDs...))
+	defer unlock()
+
+	// The meeting keeps its resources, so each of them has to be free too
+	pools := make([][]string, 0, len(meeting.ResourceIDs))
+	for _, resourceID := range meeting.ResourceIDs {
+		pools = append(pools, []string{resourceID})
+	}
+	window, seriesOpts := searchWindow(scheduleReq, meeting.Location())
+	opts := append([]algorithm.Option{algorithm.WithParticipants(participants...), algorithm.WithScorer(scorer), algorithm.WithResources(pools...)}, seriesOpts...)
+
+	for attempt := 0; attempt < maxBookingAttempts; attempt++ {
+		allEvents, err := s.participantEvents(ctx, meeting.ParticipantIDs, window, meeting.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addResourceEvents(ctx, allEvents, meeting.ResourceIDs, window, meeting.ID); err != nil {
+			return nil, err
+		}
+
+		slot, err := algorithm.FindOptimalSlot(scheduleReq, allEvents, opts...)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+		if slot == nil {
+			return nil, ErrNoAvailableSlot
+		}
+
+		meeting.StartTime = slot.Start
+		meeting.EndTime = slot.End
+		meeting.BufferBeforeMinutes = req.BufferBeforeMinutes
+		meeting.BufferAfterMinutes = req.BufferAfterMinutes
+		setSeries(meeting, meeting.Recurrence, meeting.TimeZone, slot.Skipped)
+		err = s.repo.UpdateMeetingTime(ctx, meeting)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, storageError(err, nil)
+		}
+
+		resp := &domain.ScheduleResponse{
+			MeetingID:          meeting.ID,
+			Title:              meeting.Title,
+			OrganizerID:        meeting.OrganizerID,
+			ParticipantIDs:     meeting.ParticipantIDs,
+			StartTime:          slot.Start,
+			EndTime:            slot.End,
+			Recurrence:         meeting.Recurrence,
+			SkippedOccurrences: meeting.Exceptions,
+			ResourceIDs:        meeting.ResourceIDs,
+		}
+		if req.Explain {
+			resp.Explanation = explain(slot, req.Strategy)
+		}
+		return resp, nil
+	}
+
+	return nil, ErrSlotConflict
+}
+
+// FindAvailability returns the highest scoring candidate slots for a meeting,
+// with a per-criterion score breakdown, without booking anything
+func (s *service) FindAvailability(ctx context.Context, req domain.AvailabilityRequest) (*domain.AvailabilityResponse, error) {
+	if err := validateScheduleRequest(req.ScheduleRequest); err != nil {
+		return nil, err
+	}
+	if req.Limit < 0 || req.Limit > maxAvailabilityLimit {
+		return nil, invalid("limit", CodeOutOfRange, "limit must be between 1 and %d", maxAvailabilityLimit)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultAvailabilityLimit
+	}
+	scorer, err := s.scorer(req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	invited := invitedParticipants(req.ScheduleRequest)
+	participants, err := s.loadUsers(ctx, invited)
+	if err != nil {
+		return nil, err
+	}
+	pools, err := s.resourcePools(ctx, req.Resources)
+	if err != nil {
+		return nil, err
+	}
+	organizer, err := s.organizer(ctx, req.ScheduleRequest, participants)
+	if err != nil {
+		return nil, err
+	}
+
+	window, seriesOpts := searchWindow(req.ScheduleRequest, organizer.Location())
+	allEvents, err := s.participantEvents(ctx, invited, window, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addResourceEvents(ctx, allEvents, poolResources(pools), window, ""); err != nil {
+		return nil, err
+	}
+
+	opts := append([]algorithm.Option{algorithm.WithParticipants(participants...), algorithm.WithScorer(scorer), algorithm.WithResources(pools...)}, seriesOpts...)
+	slots, err := algorithm.RankSlots(req.ScheduleRequest, allEvents, limit, opts...)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	if len(slots) == 0 {
+		return nil, ErrNoAvailableSlot
+	}
+
+	resp := &domain.AvailabilityResponse{
+		Slots: make([]domain.SlotSuggestion, 0, len(slots)),
+	}
+	for _, slot := range slots {
+		resp.Slots = append(resp.Slots, domain.SlotSuggestion{
+			StartTime:                 slot.Start,
+			EndTime:                   slot.End,
+			Score:                     slot.Score,
+			Breakdown:                 slot.Breakdown,
+			UnavailableParticipantIDs: slot.Unavailable,
+			SkippedOccurrences:        slot.Skipped,
+			ResourceIDs:               slot.Resources,
+		})
+	}
+	return resp, nil
+}
+
+// FreeBusy reports when each participant is busy within the time range and
+// when all of them are free, using the same merged calendars as scheduling
+func (s *service) FreeBusy(ctx context.Context, req domain.FreeBusyRequest) (*domain.FreeBusyResponse, error) {
+	if err := validateFreeBusyRequest(req); err != nil {
+		return nil, err
+	}
+	if _, err := s.loadUsers(ctx, req.ParticipantIDs); err != nil {
+		return nil, err
+	}
+
+	// Events just outside the range can still be busy within it through
+	// their travel time and buffers
+	padding := time.Duration(domain.MaxTravelMinutes+domain.MaxBufferMinutes) * time.Minute
+	window := domain.TimeRange{Start: req.TimeRange.Start.Add(-padding), End: req.TimeRange.End.Add(padding)}
+	allEvents, err := s.participantEvents(ctx, req.ParticipantIDs, window, "")
+	if err != nil {
+		return nil, err
+	}
+	busy, free := algorithm.FreeBusy(allEvents, req.TimeRange.Start, req.TimeRange.End)
+	return &domain.FreeBusyResponse{Busy: busy, Free: free}, nil
+}
+
+// scorer looks up the named scoring strategy; an empty name selects the default
+func (s *service) scorer(strategy string) (algorithm.Scorer, error) {
+	scorer, ok := s.scorers.Get(strategy)
+	if !ok {
+		return nil, invalid("strategy", CodeInvalid, "unknown scoring strategy %q, expected one of %s",
+			strategy, strings.Join(s.scorers.Names(), ", "))
+	}
+	return scorer, nil
+}
+
+// searchWindow returns the window whose events a search for req has to see.
+// It reaches past the time range by the longest buffer and travel time, since
+// events just outside the range can still rule out slots at its edges. For
+// recurring meetings it extends over the recurrence horizon of the last
+// candidate slot, and the returned options check each candidate's occurrences
+// as they repeat in loc.
+func searchWindow(req domain.ScheduleRequest, loc *time.Location) (domain.TimeRange, []algorithm.Option) {
+	padding := time.Duration(domain.MaxBufferMinutes+domain.MaxTravelMinutes) * time.Minute
+	window := domain.TimeRange{Start: req.TimeRange.Start.Add(-padding), End: req.TimeRange.End.Add(padding)}
+	if req.Recurrence == "" {
+		return window, nil
+	}
+	rule, err := recurrence.Parse(req.Recurrence)
+	if err != nil {
+		return window, nil
+	}
+	window.End = window.End.Add(recurrence.Horizon)
+	return window, []algorithm.Option{algorithm.WithRecurrence(rule, req.OccurrenceThreshold, loc)}
+}
+
+// setSeries makes the meeting a series following the given rule in the named
+// time zone, leaving out the skipped occurrences. It does nothing for an
+// empty rule.
+func setSeries(meeting *domain.Meeting, rule, timeZone string, skipped []time.Time) {
+	if rule == "" {
+		return
+	}
+	parsed, err := recurrence.Parse(rule)
+	if err != nil {
+		return
+	}
+	meeting.Recurrence = parsed.String()
+	meeting.TimeZone = timeZone
+	meeting.Exceptions = skipped
+	meeting.RecurrenceEnd = seriesEnd(parsed, meeting.StartTime.In(meeting.Location()), meeting.EndTime)
+}
+
+// seriesEnd returns the end of the last occurrence of a series whose first
+// occurrence is [start, end), or nil if the series does not end
+func seriesEnd(rule *recurrence.Rule, start, end time.Time) *time.Time {
+	last, ok := rule.Last(start)
+	if !ok {
+		return nil
+	}
+	lastEnd := last.Add(end.Sub(start))
+	return &lastEnd
+}
+
+// invitedParticipants returns the required participants followed by the optional ones
+func invitedParticipants(req domain.ScheduleRequest) []string {
+	return append(append([]string(nil), req.ParticipantIDs...), req.OptionalParticipantIDs...)
+}
+
+// attendees returns the invited participants who are not unavailable
+func attendees(invited, unavailable []string) []string {
+	if len(unavailable) == 0 {
+		return invited
+	}
+	busy := make(map[string]bool, len(unavailable))
+	for _, id := range unavailable {
+		busy[id] = true
+	}
+	ids := make([]string, 0, len(invited)-len(unavailable))
+	for _, id := range invited {
+		if !busy[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// explain describes how the chosen slot was scored
+func explain(slot *algorithm.TimeSlot, strategy string) *domain.ScoreExplanation {
+	if strategy == "" {
+		strategy = algorithm.DefaultStrategy
+	}
+	return &domain.ScoreExplanation{
+		Score:     slot.Score,
+		Strategy:  strategy,
+		Breakdown: slot.Breakdown,
+	}
+}
+
+// organizer returns the organizer of the requested meeting: the requested
+// one, or else the first required participant
+func (s *service) organizer(ctx context.Context, req domain.ScheduleRequest, participants []domain.User) (*domain.User, error) {
+	organizerID := req.OrganizerID
+	if organizerID == "" {
+		organizerID = req.ParticipantIDs[0]
+	}
+	for _, user := range participants {
+		if user.ID == organizerID {
+			return &user, nil
+		}
+	}
+	user, err := s.repo.GetUser(ctx, organizerID)
+	if err != nil {
+		return nil, storageError(err, &MissingUsersError{IDs: []string{organizerID}})
+	}
+	return user, nil
+}
+
+// loadUsers fetches the given users in the order of ids, failing with a
+// MissingUsersError if any of them does not exist
+func (s *service) loadUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	found, err := s.repo.GetUsers(ctx, ids)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	byID := make(map[string]domain.User, len(found))
+	for _, user := range found {
+		byID[user.ID] = user
+	}
+
+	users := make([]domain.User, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		user, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		users = append(users, user)
+	}
+	if len(missing) > 0 {
+		return nil, &MissingUsersError{IDs: missing}
+	}
+	return users, nil
+}
+
+// CreateUser registers a new user who can then be scheduled for meetings
+func (s *service) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
+	user := domain.NewUser(req.Name)
+	user.TimeZone = req.TimeZone
+	user.WorkdayStart = req.WorkdayStart
+	user.WorkdayEnd = req.WorkdayEnd
+	user.BufferBeforeMinutes = req.BufferBeforeMinutes
+	user.BufferAfterMinutes = req.BufferAfterMinutes
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return nil, storageError(err, nil)
+	}
+	return user, nil
+}
+
+func (s *service) ListUsers(ctx context.Context) ([]domain.User, error) {
+	users, err := s.repo.ListUsers(ctx)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	return users, nil
+}
+
+func (s *service) GetUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, storageError(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// UpdateUser applies the non-nil fields of req to the user
+func (s *service) UpdateUser(ctx context.Context, userID string, req domain.UpdateUserRequest) (*domain.User, error) {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, storageError(err, ErrUserNotFound)
+	}
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.TimeZone != nil {
+		user.TimeZone = *req.TimeZone
+	}
+	if req.WorkdayStart != nil {
+		user.WorkdayStart = *req.WorkdayStart
+	}
+	if req.WorkdayEnd != nil {
+		user.WorkdayEnd = *req.WorkdayEnd
+	}
+	if req.BufferBeforeMinutes != nil {
+		user.BufferBeforeMinutes = *req.BufferBeforeMinutes
+	}
+	if req.BufferAfterMinutes != nil {
+		user.BufferAfterMinutes = *req.BufferAfterMinutes
+	}
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, storageError(err, nil)
+	}
+	return user, nil
+}
+
+// DeleteUser removes a user together with their calendar; see
+// Repository.DeleteUser for what happens to the meetings they take part in
+func (s *service) DeleteUser(ctx context.Context, userID string) error {
+	if _, err := s.repo.GetUser(ctx, userID); err != nil {
+		return storageError(err, ErrUserNotFound)
+	}
+	if err := s.repo.DeleteUser(ctx, userID); err != nil {
+		return storageError(err, nil)
+	}
+	return nil
+}
+
+// CreateEvent blocks time on a user's calendar, e.g. for focus time or an
+// existing commitment. The event may not overlap anything already booked.
+func (s *service) CreateEvent(ctx context.Context, userID string, req domain.EventRequest) (*domain.CalendarEvent, error) {
+	if err := validateEventRequest(req); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetUser(ctx, userID); err != nil {
+		return nil, storageError(err, ErrUserNotFound)
+	}
+
+	unlock := s.locks.Lock([]string{userID})
+	defer unlock()
+
+	if err := s.checkEventOverlap(ctx, userID, req, ""); err != nil {
+		return nil, err
+	}
+
+	event := domain.NewCalendarEvent(eventTitle(req), req.StartTime, req.EndTime, userID)
+	event.TravelMinutes = req.TravelMinutes
+	if err := s.repo.CreateEvent(ctx, event); err != nil {
+		return nil, storageError(err, nil)
+	}
+	return event, nil
+}
+
+// UpdateEvent changes the title and time of one of the user's own events
+func (s *service) UpdateEvent(ctx context.Context, userID, eventID string, req domain.EventRequest) (*domain.CalendarEvent, error) {
+	if err := validateEventRequest(req); err != nil {
+		return nil, err
+	}
+
+	unlock := s.locks.Lock([]string{userID})
+	defer unlock()
+
+	event, err := s.userEvent(ctx, userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEventOverlap(ctx, userID, req, eventID); err != nil {
+		return nil, err
+	}
+
+	event.Title = eventTitle(req)
+	event.StartTime = req.StartTime
+	event.EndTime = req.EndTime
+	event.TravelMinutes = req.TravelMinutes
+	event.UpdatedAt = time.Now()
+	if err := s.repo.UpdateEvent(ctx, event); err != nil {
+		return nil, storageError(err, nil)
+	}
+	return event, nil
+}
+
+// DeleteEvent removes one of the user's own events
+func (s *service) DeleteEvent(ctx context.Context, userID, eventID string) error {
+	unlock := s.locks.Lock([]string{userID})
+	defer unlock()
+
+	if _, err := s.userEvent(ctx, userID, eventID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteEvent(ctx, eventID); err != nil {
+		return storageError(err, nil)
+	}
+	return nil
+}
+
+// CreateResource registers a room or piece of equipment that meetings can book
+func (s *service) CreateResource(ctx context.Context, req domain.CreateResourceRequest) (*domain.Resource, error) {
+	kind := req.Kind
+	if kind == "" {
+		kind = domain.ResourceRoom
+	}
+	resource := domain.NewResource(req.Name, kind)
+	resource.Capacity = req.Capacity
+	resource.Building = req.Building
+	resource.Attributes = req.Attributes
+	if err := validateResource(resource); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateResource(ctx, resource); err != nil {
+		return nil, storageError(err, nil)
+	}
+	return resource, nil
+}
+
+func (s *service) ListResources(ctx context.Context) ([]domain.Resource, error) {
+	resources, err := s.repo.ListResources(ctx)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	return resources, nil
+}
+
+func (s *service) GetResource(ctx context.Context, resourceID string) (*domain.Resource, error) {
+	resource, err := s.repo.GetResource(ctx, resourceID)
+	if err != nil {
+		return nil, storageError(err, ErrResourceNotFound)
+	}
+	return resource, nil
+}
+
+// GetResourceCalendar returns the meetings a resource is booked for within
+// the window, with recurring meetings expanded into their occurrences
+func (s *service) GetResourceCalendar(ctx context.Context, resourceID string, start, end time.Time) ([]domain.CalendarEvent, error) {
+	if _, err := s.repo.GetResource(ctx, resourceID); err != nil {
+		return nil, storageError(err, ErrResourceNotFound)
+	}
+
+	byResource, err := s.repo.GetEventsForResources(ctx, []string{resourceID}, start, end)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	events, err := recurrence.ExpandAll(byResource[resourceID], start, end)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	return events, nil
+}
+
+// userEvent loads an event that belongs to the user and is not part of a
+// meeting, since those must stay in sync across participants
+func (s *service) userEvent(ctx context.Context, userID, eventID string) (*domain.CalendarEvent, error) {
+	event, err := s.repo.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, storageError(err, ErrEventNotFound)
+	}
+	if event.UserID != userID {
+		return nil, ErrEventNotFound
+	}
+	if event.MeetingID != nil {
+		return nil, ErrMeetingEvent
+	}
+	return event, nil
+}
+
+// checkEventOverlap returns ErrEventConflict if the requested time overlaps
+// any of the user's events other than excludeEventID. Travel time may overlap
+// other travel time, but neither event's travel may overlap the other event,
+// and no event may overlap a meeting's buffers.
+func (s *service) checkEventOverlap(ctx context.Context, userID string, req domain.EventRequest, excludeEventID string) error {
+	padding := time.Duration(2*domain.MaxTravelMinutes+domain.MaxBufferMinutes) * time.Minute
+	start, end := req.StartTime.Add(-padding), req.EndTime.Add(padding)
+	events, err := s.repo.GetUserEvents(ctx, userID, start, end)
+	if err != nil {
+		return storageError(err, nil)
+	}
+	events, err = recurrence.ExpandAll(events, start, end)
+	if err != nil {
+		return ErrInternalError
+	}
+
+	requested := domain.CalendarEvent{StartTime: req.StartTime, EndTime: req.EndTime, TravelMinutes: req.TravelMinutes}
+	for _, event := range events {
+		if event.ID == excludeEventID {
+			continue
+		}
+		if !algorithm.IsSlotAvailable(req.StartTime, req.EndTime, map[string][]domain.CalendarEvent{userID: {event}}) ||
+			!algorithm.IsSlotAvailable(event.StartTime, event.EndTime, map[string][]domain.CalendarEvent{userID: {requested}}) {
+			return ErrEventConflict
+		}
+	}
+	return nil
+}
+
+func eventTitle(req domain.EventRequest) string {
+	if req.Title == "" {
+		return "Busy"
+	}
+	return req.Title
+}
+
+// participantEvents loads every participant's events within the time range,
+// with recurring events expanded into their occurrences, leaving out the
+// events that belong to excludeMeetingID (if set)
+func (s *service) participantEvents(ctx context.Context, participantIDs []string, timeRange domain.TimeRange, excludeMeetingID string) (map[string][]domain.CalendarEvent, error) {
+	byUser, err := s.repo.GetEventsForUsers(ctx, participantIDs, timeRange.Start, timeRange.End)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	allEvents := make(map[string][]domain.CalendarEvent)
+	for _, userID := range participantIDs {
+		events := byUser[userID]
+		if excludeMeetingID != "" {
+			events = withoutMeeting(events, excludeMeetingID)
+		}
+		allEvents[userID], err = recurrence.ExpandAll(events, timeRange.Start, timeRange.End)
+		if err != nil {
+			return nil, ErrInternalError
+		}
+	}
+	return allEvents, nil
+}
+
+// resourcePools finds the resources that meet each requirement. Each pool
+// lists the smallest rooms first, so that larger rooms stay free for larger
+// meetings. It fails with ErrNoMatchingResource if a requirement cannot be met
+// by any resource at all.
+func (s *service) resourcePools(ctx context.Context, requirements []domain.ResourceRequirement) ([][]string, error) {
+	if len(requirements) == 0 {
+		return nil, nil
+	}
+	resources, err := s.repo.ListResources(ctx)
+	if err != nil {
+		return nil, storageError(err, nil)
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].Capacity < resources[j].Capacity
+	})
+
+	pools := make([][]string, len(requirements))
+	for i, requirement := range requirements {
+		for _, resource := range resources {
+			if requirement.Matches(resource) {
+				pools[i] = append(pools[i], resource.ID)
+			}
+		}
+		if len(pools[i]) == 0 {
+			return nil, ErrNoMatchingResource
+		}
+	}
+	return pools, nil
+}
+
+// poolResources returns every resource of the pools once
+func poolResources(pools [][]string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, pool := range pools {
+		for _, id := range pool {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// addResourceEvents adds the expanded calendars of the resources to events,
+// keyed by resource ID as algorithm.WithResources expects
+func (s *service) addResourceEvents(ctx context.Context, events map[string][]domain.CalendarEvent, resourceIDs []string, timeRange domain.TimeRange, excludeMeetingID string) error {
+	byResource, err := s.repo.GetEventsForResources(ctx, resourceIDs, timeRange.Start, timeRange.End)
+	if err != nil {
+		return storageError(err, nil)
+	}
+	for _, resourceID := range resourceIDs {
+		resourceEvents := byResource[resourceID]
+		if excludeMeetingID != "" {
+			resourceEvents = withoutMeeting(resourceEvents, excludeMeetingID)
+		}
+		events[resourceID], err = recurrence.ExpandAll(resourceEvents, timeRange.Start, timeRange.End)
+		if err != nil {
+			return ErrInternalError
+		}
+	}
+	return nil
+}
+
+func withoutMeeting(events []domain.CalendarEvent, meetingID string) []domain.CalendarEvent {
+	filtered := make([]domain.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		if event.MeetingID != nil && *event.MeetingID == meetingID {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
+
 func validateScheduleRequest(req domain.ScheduleRequest) error {
 	if len(req.ParticipantIDs) == 0 {
-		return errors.New("at least one participant is required")
+		return invalid("participantIds", CodeRequired, "at least one participant is required")
 	}
 
-	// Check for duplicate participant IDs
+	// Check for duplicate participant IDs, including between the required
+	// and the optional participants
 	participantMap := make(map[string]bool)
-	for _, id := range req.ParticipantIDs {
+	for _, id := range invitedParticipants(req) {
 		if id == "" {
-			return errors.New("participant ID cannot be empty")
+			return invalid("participantIds", CodeRequired, "participant ID cannot be empty")
 		}
 		if participantMap[id] {
-			return errors.New("duplicate participant IDs are not allowed")
+			return invalid("participantIds", CodeDuplicate, "duplicate participant IDs are not allowed")
 		}
 		participantMap[id] = true
 	}
 
+	if req.MinAttendees < 0 || req.MinAttendees > len(participantMap) {
+		return invalid("minAttendees", CodeOutOfRange, "minimum attendees must be between 0 and the number of participants (%d)", len(participantMap))
+	}
+
 	if req.DurationMinutes <= 0 {
-		return errors.New("duration must be greater than 0 minutes")
+		return invalid("durationMinutes", CodeOutOfRange, "duration must be greater than 0 minutes")
 	}
 	if req.DurationMinutes > 480 { // 8 hours max
-		return errors.New("duration cannot exceed 8 hours (480 minutes)")
+		return invalid("durationMinutes", CodeOutOfRange, "duration cannot exceed 8 hours (480 minutes)")
 	}
 
 	if req.TimeRange.Start.IsZero() {
-		return errors.New("start time is required")
+		return invalid("timeRange.start", CodeRequired, "start time is required")
 	}
 	if req.TimeRange.End.IsZero() {
-		return errors.New("end time is required")
+		return invalid("timeRange.end", CodeRequired, "end time is required")
 	}
 	if req.TimeRange.Start.After(req.TimeRange.End) {
-		return errors.New("start time must be before end time")
+		return invalid("timeRange", CodeInvalid, "start time must be before end time")
 	}
 
 	now := time.Now()
 	if req.TimeRange.Start.Before(now) {
-		return errors.New("start time cannot be in the past. Please enter a valid future start date.")
+		return invalid("timeRange.start", CodeOutOfRange, "start time cannot be in the past. Please enter a valid future start date.")
 	}
 
 	maxFuture := now.AddDate(1, 0, 0)
 	if req.TimeRange.End.After(maxFuture) {
-		return errors.New("end time cannot be more than 1 year in the future")
+		return invalid("timeRange.end", CodeOutOfRange, "end time cannot be more than 1 year in the future")
 	}
 
 	duration := time.Duration(req.DurationMinutes) * time.Minute
 	if req.TimeRange.Start.Add(duration).After(req.TimeRange.End) {
-		return errors.New("duration does not fit within the specified time range")
+		return invalid("durationMinutes", CodeOutOfRange, "duration does not fit within the specified time range")
 	}
 
+	switch req.GranularityMinutes {
+	case 0, 5, 10, 15, 30, 60:
+	default:
+		return invalid("granularityMinutes", CodeInvalid, "granularity must be one of 5, 10, 15, 30 or 60 minutes")
+	}
+
+	switch req.Alignment {
+	case "", domain.AlignNone, domain.AlignGranularity, domain.AlignHalfHour, domain.AlignHour:
+	default:
+		return invalid("alignment", CodeInvalid, "alignment must be one of %q, %q, %q or %q",
+			domain.AlignNone, domain.AlignGranularity, domain.AlignHalfHour, domain.AlignHour)
+	}
+
+	if req.Recurrence != "" {
+		if _, err := recurrence.Parse(req.Recurrence); err != nil {
+			return invalid("recurrence", CodeInvalid, "%v", err)
+		}
+	}
+	if req.OccurrenceThreshold < 0 || req.OccurrenceThreshold > 1 {
+		return invalid("occurrenceThreshold", CodeOutOfRange, "occurrence threshold must be between 0 and 1")
+	}
+
+	for i, requirement := range req.Resources {
+		switch requirement.Kind {
+		case "", domain.ResourceRoom, domain.ResourceEquipment:
+		default:
+			return invalid(fmt.Sprintf("resources[%d].kind", i), CodeInvalid,
+				"resource kind must be %q or %q", domain.ResourceRoom, domain.ResourceEquipment)
+		}
+		if requirement.MinCapacity < 0 {
+			return invalid(fmt.Sprintf("resources[%d].minCapacity", i), CodeOutOfRange, "minimum capacity cannot be negative")
+		}
+	}
+
+	return validateBuffers(req.BufferBeforeMinutes, req.BufferAfterMinutes)
+}
+
+func validateFreeBusyRequest(req domain.FreeBusyRequest) error {
+	if len(req.ParticipantIDs) == 0 {
+		return invalid("participantIds", CodeRequired, "at least one participant is required")
+	}
+	participantMap := make(map[string]bool)
+	for _, id := range req.ParticipantIDs {
+		if id == "" {
+			return invalid("participantIds", CodeRequired, "participant ID cannot be empty")
+		}
+		if participantMap[id] {
+			return invalid("participantIds", CodeDuplicate, "duplicate participant IDs are not allowed")
+		}
+		participantMap[id] = true
+	}
+
+	if req.TimeRange.Start.IsZero() {
+		return invalid("timeRange.start", CodeRequired, "start time is required")
+	}
+	if req.TimeRange.End.IsZero() {
+		return invalid("timeRange.end", CodeRequired, "end time is required")
+	}
+	if !req.TimeRange.Start.Before(req.TimeRange.End) {
+		return invalid("timeRange", CodeInvalid, "start time must be before end time")
+	}
+	if req.TimeRange.End.Sub(req.TimeRange.Start) > recurrence.Horizon {
+		return invalid("timeRange", CodeOutOfRange, "time range cannot exceed 1 year")
+	}
 	return nil
 }
 
+func validateEventRequest(req domain.EventRequest) error {
+	if req.StartTime.IsZero() {
+		return invalid("startTime", CodeRequired, "start time is required")
+	}
+	if req.EndTime.IsZero() {
+		return invalid("endTime", CodeRequired, "end time is required")
+	}
+	if !req.StartTime.Before(req.EndTime) {
+		return invalid("endTime", CodeInvalid, "start time must be before end time")
+	}
+	if req.TravelMinutes < 0 || req.TravelMinutes > domain.MaxTravelMinutes {
+		return invalid("travelMinutes", CodeOutOfRange, "travel time must be between 0 and %d minutes", domain.MaxTravelMinutes)
+	}
+	return nil
+}
+
+func validateUser(user *domain.User) error {
+	if strings.TrimSpace(user.Name) == "" {
+		return invalid("name", CodeRequired, "name is required")
+	}
+	if user.TimeZone != "" {
+		if _, err := time.LoadLocation(user.TimeZone); err != nil {
+			return invalid("timeZone", CodeInvalid, "unknown time zone %q", user.TimeZone)
+		}
+	}
+	if user.WorkdayStart != 0 || user.WorkdayEnd != 0 {
+		if user.WorkdayStart < 0 || user.WorkdayEnd > 24 || user.WorkdayStart >= user.WorkdayEnd {
+			return invalid("workdayStart", CodeOutOfRange, "working hours must satisfy 0 <= workdayStart < workdayEnd <= 24")
+		}
+	}
+	return validateBuffers(user.BufferBeforeMinutes, user.BufferAfterMinutes)
+}
+
+func validateBuffers(before, after int) error {
+	if before < 0 || before > domain.MaxBufferMinutes {
+		return invalid("bufferBeforeMinutes", CodeOutOfRange, "buffers must be between 0 and %d minutes", domain.MaxBufferMinutes)
+	}
+	if after < 0 || after > domain.MaxBufferMinutes {
+		return invalid("bufferAfterMinutes", CodeOutOfRange, "buffers must be between 0 and %d minutes", domain.MaxBufferMinutes)
+	}
+	return nil
+}
+
+func validateResource(resource *domain.Resource) error {
+	if strings.TrimSpace(resource.Name) == "" {
+		return invalid("name", CodeRequired, "name is required")
+	}
+	if resource.Kind != domain.ResourceRoom && resource.Kind != domain.ResourceEquipment {
+		return invalid("kind", CodeInvalid, "kind must be %q or %q", domain.ResourceRoom, domain.ResourceEquipment)
+	}
+	if resource.Capacity < 0 {
+		return invalid("capacity", CodeOutOfRange, "capacity cannot be negative")
+	}
+	for _, attribute := range resource.Attributes {
+		if strings.TrimSpace(attribute) == "" {
+			return invalid("attributes", CodeRequired, "attributes cannot be empty")
+		}
+	}
+	return nil
+}
+
+// storageError translates a failed repository call. A missing record is
+// reported as notFound, if given. A write aborted by a concurrent one is
+// reported as ErrSlotConflict, a lost connection or timeout as ErrUnavailable
+// and any other failure as ErrInternalError, all wrapping the cause so that
+// it is logged.
+func storageError(err, notFound error) error {
+	switch {
+	case notFound != nil && errors.Is(err, repository.ErrNotFound):
+		return notFound
+	case errors.Is(err, repository.ErrConflict):
+		return fmt.Errorf("%w: %v", ErrSlotConflict, err)
+	case errors.Is(err, repository.ErrUnavailable):
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	default:
+		return fmt.Errorf("%w: %v", ErrInternalError, err)
+	}
+}
+
 func generateMeetingID() string {
 	return uuid.New().String()
 }
@@ -2,58 +2,148 @@ package service
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/meeting-scheduler/internal/domain"
+	"github.com/meeting-scheduler/pkg/algorithm"
+	"github.com/meeting-scheduler/pkg/repository"
 )
 
-// MockRepository implements the Repository interface for testing
+// MockRepository is the in-memory repository with failures injected for
+// testing
 type MockRepository struct {
-	users  map[string]*domain.User
-	events map[string][]domain.CalendarEvent
+	*repository.MemoryRepository
+
+	mu sync.Mutex
+	// failEventsFor makes CreateMeetingWithEvents fail when booking this user
+	failEventsFor string
+	// getUserErr, if set, is returned by GetUser and GetUsers, simulating a
+	// lost connection
+	getUserErr error
+	// beforeBooking runs inside CreateMeetingWithEvents before the conflict
+	// check, simulating a write from another service instance
+	beforeBooking func()
+	// booked lists every meeting CreateMeetingWithEvents was asked to store
+	booked []string
 }
 
 func NewMockRepository() *MockRepository {
-	return &MockRepository{
-		users:  make(map[string]*domain.User),
-		events: make(map[string][]domain.CalendarEvent),
-	}
+	return &MockRepository{MemoryRepository: repository.NewMemoryRepository()}
 }
 
 func (m *MockRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
-	user, exists := m.users[id]
-	if !exists {
-		return nil, ErrUserNotFound
+	if m.getUserErr != nil {
+		return nil, m.getUserErr
+	}
+	return m.MemoryRepository.GetUser(ctx, id)
+}
+
+func (m *MockRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	if m.getUserErr != nil {
+		return nil, m.getUserErr
 	}
-	return user, nil
+	return m.MemoryRepository.GetUsers(ctx, ids)
 }
 
-func (m *MockRepository) GetUserEvents(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error) {
-	events := m.events[userID]
-	var filtered []domain.CalendarEvent
+func (m *MockRepository) CreateMeetingWithEvents(ctx context.Context, meeting *domain.Meeting, events []*domain.CalendarEvent) error {
+	m.mu.Lock()
+	m.booked = append(m.booked, meeting.ID)
+	beforeBooking := m.beforeBooking
+	m.beforeBooking = nil
+	m.mu.Unlock()
+
 	for _, event := range events {
-		if (event.StartTime.Equal(start) || event.StartTime.After(start)) &&
-			(event.EndTime.Equal(end) || event.EndTime.Before(end)) {
-			filtered = append(filtered, event)
+		if m.failEventsFor != "" && event.UserID == m.failEventsFor {
+			return errors.New("insert failed")
 		}
 	}
-	return filtered, nil
+	if beforeBooking != nil {
+		beforeBooking()
+	}
+	return m.MemoryRepository.CreateMeetingWithEvents(ctx, meeting, events)
 }
 
-func (m *MockRepository) CreateEvent(ctx context.Context, event *domain.CalendarEvent) error {
-	m.events[event.UserID] = append(m.events[event.UserID], *event)
-	return nil
+// addUsers stores the users, failing the test on error
+func (m *MockRepository) addUsers(t *testing.T, users ...*domain.User) {
+	t.Helper()
+	for _, user := range users {
+		if err := m.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("Failed to add user %s: %v", user.ID, err)
+		}
+	}
 }
 
-func TestSchedule(t *testing.T) {
-	// Helper function to create time
-	parseTime := func(s string) time.Time {
-		t, _ := time.Parse(time.RFC3339, s)
-		return t
+// setEvents replaces the user's calendar with the given events, filling in
+// the user and any missing event IDs
+func (m *MockRepository) setEvents(t *testing.T, userID string, events ...domain.CalendarEvent) {
+	t.Helper()
+	ctx := context.Background()
+	for _, event := range m.userEvents(t, userID) {
+		if err := m.DeleteEvent(ctx, event.ID); err != nil {
+			t.Fatalf("Failed to delete event %s: %v", event.ID, err)
+		}
+	}
+	for _, event := range events {
+		event.UserID = userID
+		if event.ID == "" {
+			event.ID = uuid.New().String()
+		}
+		if err := m.CreateEvent(ctx, &event); err != nil {
+			t.Fatalf("Failed to add event %s: %v", event.ID, err)
+		}
 	}
+}
+
+// userEvents returns every event on the user's calendar
+func (m *MockRepository) userEvents(t *testing.T, userID string) []domain.CalendarEvent {
+	t.Helper()
+	events, err := m.GetUserEvents(context.Background(), userID, time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to load events of %s: %v", userID, err)
+	}
+	return events
+}
+
+// calendars returns every event on the calendars of the given users
+func (m *MockRepository) calendars(t *testing.T, userIDs ...string) map[string][]domain.CalendarEvent {
+	t.Helper()
+	calendars := make(map[string][]domain.CalendarEvent, len(userIDs))
+	for _, userID := range userIDs {
+		calendars[userID] = m.userEvents(t, userID)
+	}
+	return calendars
+}
+
+// storedMeetings counts the meetings that were booked and still exist
+func (m *MockRepository) storedMeetings() int {
+	m.mu.Lock()
+	booked := append([]string(nil), m.booked...)
+	m.mu.Unlock()
 
+	stored := 0
+	for _, id := range booked {
+		if _, err := m.GetMeeting(context.Background(), id); err == nil {
+			stored++
+		}
+	}
+	return stored
+}
+
+// tomorrowAt returns the given hour of tomorrow in UTC, so scheduling tests
+// always use a time range that passes the "not in the past" validation
+func tomorrowAt(hour int) time.Time {
+	day := time.Now().UTC().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	return day.Add(time.Duration(hour) * time.Hour)
+}
+
+func TestSchedule(t *testing.T) {
 	// Create mock repository with test data
 	repo := NewMockRepository()
 
@@ -63,7 +153,7 @@ func TestSchedule(t *testing.T) {
 		{ID: "user2", Name: "Bob"},
 	}
 	for _, user := range users {
-		repo.users[user.ID] = user
+		repo.addUsers(t, user)
 	}
 
 	// Create service
@@ -72,7 +162,7 @@ func TestSchedule(t *testing.T) {
 	tests := []struct {
 		name         string
 		request      domain.ScheduleRequest
-		setupEvents  func()
+		setupEvents  func(*testing.T)
 		expectError  bool
 		errorType    error
 		validateResp func(*testing.T, *domain.ScheduleResponse)
@@ -83,12 +173,13 @@ func TestSchedule(t *testing.T) {
 				ParticipantIDs:  []string{"user1", "user2"},
 				DurationMinutes: 60,
 				TimeRange: domain.TimeRange{
-					Start: parseTime("2025-09-01T09:00:00Z"),
-					End:   parseTime("2025-09-01T17:00:00Z"),
+					Start: tomorrowAt(9),
+					End:   tomorrowAt(17),
 				},
 			},
-			setupEvents: func() {
-				repo.events = make(map[string][]domain.CalendarEvent)
+			setupEvents: func(t *testing.T) {
+				repo.setEvents(t, "user1")
+				repo.setEvents(t, "user2")
 			},
 			expectError: false,
 			validateResp: func(t *testing.T, resp *domain.ScheduleResponse) {
@@ -106,25 +197,19 @@ func TestSchedule(t *testing.T) {
 				ParticipantIDs:  []string{"user1", "user2"},
 				DurationMinutes: 60,
 				TimeRange: domain.TimeRange{
-					Start: parseTime("2025-09-01T09:00:00Z"),
-					End:   parseTime("2025-09-01T11:00:00Z"),
+					Start: tomorrowAt(9),
+					End:   tomorrowAt(11),
 				},
 			},
-			setupEvents: func() {
-				repo.events = map[string][]domain.CalendarEvent{
-					"user1": {
-						{
-							StartTime: parseTime("2025-09-01T09:00:00Z"),
-							EndTime:   parseTime("2025-09-01T10:00:00Z"),
-						},
-					},
-					"user2": {
-						{
-							StartTime: parseTime("2025-09-01T10:00:00Z"),
-							EndTime:   parseTime("2025-09-01T11:00:00Z"),
-						},
-					},
-				}
+			setupEvents: func(t *testing.T) {
+				repo.setEvents(t, "user1", domain.CalendarEvent{
+					StartTime: tomorrowAt(9),
+					EndTime:   tomorrowAt(10),
+				})
+				repo.setEvents(t, "user2", domain.CalendarEvent{
+					StartTime: tomorrowAt(10),
+					EndTime:   tomorrowAt(11),
+				})
 			},
 			expectError: true,
 			errorType:   ErrNoAvailableSlot,
@@ -135,12 +220,13 @@ func TestSchedule(t *testing.T) {
 				ParticipantIDs:  []string{"user1", "nonexistent"},
 				DurationMinutes: 60,
 				TimeRange: domain.TimeRange{
-					Start: parseTime("2025-09-01T09:00:00Z"),
-					End:   parseTime("2025-09-01T17:00:00Z"),
+					Start: tomorrowAt(9),
+					End:   tomorrowAt(17),
 				},
 			},
-			setupEvents: func() {
-				repo.events = make(map[string][]domain.CalendarEvent)
+			setupEvents: func(t *testing.T) {
+				repo.setEvents(t, "user1")
+				repo.setEvents(t, "user2")
 			},
 			expectError: true,
 			errorType:   ErrUserNotFound,
@@ -150,7 +236,7 @@ func TestSchedule(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup test events
-			tt.setupEvents()
+			tt.setupEvents(t)
 
 			// Execute test
 			resp, err := svc.Schedule(context.Background(), tt.request)
@@ -160,7 +246,7 @@ func TestSchedule(t *testing.T) {
 				if err == nil {
 					t.Error("Expected error but got none")
 				}
-				if err != tt.errorType {
+				if !errors.Is(err, tt.errorType) {
 					t.Errorf("Expected error %v but got %v", tt.errorType, err)
 				}
 				return
@@ -182,7 +268,7 @@ func TestGetUserCalendar(t *testing.T) {
 
 	// Add test user
 	user := &domain.User{ID: "user1", Name: "Alice"}
-	repo.users[user.ID] = user
+	repo.addUsers(t, user)
 
 	// Add test events
 	startTime := time.Now()
@@ -202,7 +288,7 @@ func TestGetUserCalendar(t *testing.T) {
 			UserID:    user.ID,
 		},
 	}
-	repo.events[user.ID] = events
+	repo.setEvents(t, user.ID, events...)
 
 	// Create service
 	svc := NewService(repo)
@@ -270,9 +356,10 @@ func TestGetUserCalendar(t *testing.T) {
 
 func TestValidateScheduleRequest(t *testing.T) {
 	tests := []struct {
-		name    string
-		request domain.ScheduleRequest
-		wantErr bool
+		name      string
+		request   domain.ScheduleRequest
+		wantErr   bool
+		wantField string
 	}{
 		{
 			name: "Valid request",
@@ -296,7 +383,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(2 * time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "participantIds",
 		},
 		{
 			name: "Duplicate participant IDs",
@@ -308,7 +396,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(2 * time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "participantIds",
 		},
 		{
 			name: "Empty participant ID",
@@ -320,7 +409,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(2 * time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "participantIds",
 		},
 		{
 			name: "Invalid duration - zero",
@@ -332,7 +422,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(2 * time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "durationMinutes",
 		},
 		{
 			name: "Invalid duration - negative",
@@ -344,7 +435,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(2 * time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "durationMinutes",
 		},
 		{
 			name: "Invalid duration - too long",
@@ -356,7 +448,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(2 * time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "durationMinutes",
 		},
 		{
 			name: "Start time in the past",
@@ -368,7 +461,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "timeRange.start",
 		},
 		{
 			name: "End time before start time",
@@ -380,7 +474,50 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(time.Hour),
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "timeRange",
+		},
+		{
+			name: "Unsupported granularity",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1", "user2"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: time.Now().Add(time.Hour),
+					End:   time.Now().Add(3 * time.Hour),
+				},
+				GranularityMinutes: 7,
+			},
+			wantErr:   true,
+			wantField: "granularityMinutes",
+		},
+		{
+			name: "Unknown alignment",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1", "user2"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: time.Now().Add(time.Hour),
+					End:   time.Now().Add(3 * time.Hour),
+				},
+				Alignment: "quarter",
+			},
+			wantErr:   true,
+			wantField: "alignment",
+		},
+		{
+			name: "Granularity with alignment",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1", "user2"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: time.Now().Add(time.Hour),
+					End:   time.Now().Add(3 * time.Hour),
+				},
+				GranularityMinutes: 30,
+				Alignment:          domain.AlignHour,
+			},
+			wantErr: false,
 		},
 		{
 			name: "Duration doesn't fit in time range",
@@ -392,7 +529,8 @@ func TestValidateScheduleRequest(t *testing.T) {
 					End:   time.Now().Add(time.Hour + 30*time.Minute), // Only 30 minutes
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantField: "durationMinutes",
 		},
 	}
 
@@ -402,7 +540,1341 @@ func TestValidateScheduleRequest(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateScheduleRequest() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if err == nil {
+				return
+			}
+			var validation *ValidationError
+			if !errors.As(err, &validation) || validation.Field != tt.wantField {
+				t.Errorf("validateScheduleRequest() error = %#v, want a ValidationError for field %q", err, tt.wantField)
+			}
+			if !errors.Is(err, ErrInvalidRequest) {
+				t.Errorf("validateScheduleRequest() error = %v, want it to match ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestGetMeeting(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}} {
+		repo.addUsers(t, user)
+	}
+	svc := NewService(repo)
+
+	resp, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2"},
+		DurationMinutes: 30,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+		Title: "Planning",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	meeting, err := svc.GetMeeting(context.Background(), resp.MeetingID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meeting.Title != "Planning" {
+		t.Errorf("Expected title Planning, got %q", meeting.Title)
+	}
+	if meeting.OrganizerID != "user1" {
+		t.Errorf("Expected organizer to default to the first participant, got %q", meeting.OrganizerID)
+	}
+	if len(meeting.ParticipantIDs) != 2 {
+		t.Errorf("Expected 2 participants, got %v", meeting.ParticipantIDs)
+	}
+	for userID, events := range repo.calendars(t, "user1", "user2") {
+		for _, event := range events {
+			if event.MeetingID == nil || *event.MeetingID != resp.MeetingID {
+				t.Errorf("Event for %s is not linked to meeting %s", userID, resp.MeetingID)
+			}
+		}
+	}
+
+	if _, err := svc.GetMeeting(context.Background(), "nonexistent"); err != ErrMeetingNotFound {
+		t.Errorf("Expected error %v but got %v", ErrMeetingNotFound, err)
+	}
+}
+
+func TestScheduleSeesBoundarySpanningEvents(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}} {
+		repo.addUsers(t, user)
+	}
+	// user1 is busy from 8 to 10 and user2 from 16 to 18, so both events
+	// only partially overlap the 9 to 17 search window
+	repo.setEvents(t, "user1",
+		domain.CalendarEvent{ID: "early", StartTime: tomorrowAt(8), EndTime: tomorrowAt(10), UserID: "user1"},
+	)
+	repo.setEvents(t, "user2",
+		domain.CalendarEvent{ID: "late", StartTime: tomorrowAt(16), EndTime: tomorrowAt(18), UserID: "user2"},
+	)
+	svc := NewService(repo)
+
+	resp, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StartTime.Before(tomorrowAt(10)) {
+		t.Errorf("Meeting at %v overlaps user1's event running into the window", resp.StartTime)
+	}
+	if resp.EndTime.After(tomorrowAt(16)) {
+		t.Errorf("Meeting ending at %v overlaps user2's event running out of the window", resp.EndTime)
+	}
+
+	// A window entirely inside a longer event is fully booked
+	_, err = svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 30,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(8).Add(15 * time.Minute), End: tomorrowAt(9).Add(45 * time.Minute)},
+	})
+	if err != ErrNoAvailableSlot {
+		t.Errorf("Expected error %v but got %v", ErrNoAvailableSlot, err)
+	}
+
+	events, err := svc.GetUserCalendar(context.Background(), "user1", tomorrowAt(9), tomorrowAt(12))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "early" {
+		t.Errorf("Expected the calendar to include the event running into the window, got %+v", events)
+	}
+
+	_, err = svc.CreateEvent(context.Background(), "user2", domain.EventRequest{StartTime: tomorrowAt(17), EndTime: tomorrowAt(19)})
+	if err != ErrEventConflict {
+		t.Errorf("Expected error %v for a partially overlapping event but got %v", ErrEventConflict, err)
+	}
+}
+
+func TestScheduleIsAtomic(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}, {ID: "user3", Name: "Charlie"}} {
+		repo.addUsers(t, user)
+	}
+	repo.failEventsFor = "user3"
+	svc := NewService(repo)
+
+	_, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2", "user3"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+	})
+	if !errors.Is(err, ErrInternalError) || !strings.Contains(err.Error(), "insert failed") {
+		t.Fatalf("Expected error %v wrapping the cause but got %v", ErrInternalError, err)
+	}
+	if stored := repo.storedMeetings(); stored != 0 {
+		t.Errorf("Expected no meeting to be stored, got %d", stored)
+	}
+	for userID, events := range repo.calendars(t, "user1", "user2", "user3") {
+		if len(events) != 0 {
+			t.Errorf("Expected no events for %s, got %d", userID, len(events))
+		}
+	}
+}
+
+func TestScheduleReportsMissingUsers(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	svc := NewService(repo)
+
+	_, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:         []string{"ghost1", "user1", "ghost2"},
+		OptionalParticipantIDs: []string{"ghost3"},
+		DurationMinutes:        30,
+		TimeRange:              domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	var missing *MissingUsersError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingUsersError but got %v", err)
+	}
+	if got := strings.Join(missing.IDs, ","); got != "ghost1,ghost2,ghost3" {
+		t.Errorf("Expected the missing IDs ghost1,ghost2,ghost3 but got %s", got)
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected %v to match ErrUserNotFound", err)
+	}
+}
+
+func TestStorageErrors(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	if _, err := svc.GetUser(ctx, "missing"); err != ErrUserNotFound {
+		t.Errorf("Expected error %v for a missing user but got %v", ErrUserNotFound, err)
+	}
+	if _, err := svc.GetMeeting(ctx, "missing"); err != ErrMeetingNotFound {
+		t.Errorf("Expected error %v for a missing meeting but got %v", ErrMeetingNotFound, err)
+	}
+
+	repo.getUserErr = fmt.Errorf("get user: %w: %w", repository.ErrUnavailable, driver.ErrBadConn)
+	_, err := svc.GetUser(ctx, "user1")
+	if !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), "bad connection") {
+		t.Errorf("Expected error %v wrapping the cause when the storage is unreachable but got %v", ErrUnavailable, err)
+	}
+	_, err = svc.Schedule(ctx, domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 30,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected Schedule to fail with %v when the storage is unreachable but got %v", ErrUnavailable, err)
+	}
+
+	repo.getUserErr = fmt.Errorf("get user: %w: %w", repository.ErrConflict, errors.New("Error 1213: Deadlock found"))
+	if _, err := svc.GetUser(ctx, "user1"); !errors.Is(err, ErrSlotConflict) {
+		t.Errorf("Expected error %v when a concurrent write aborts the call but got %v", ErrSlotConflict, err)
+	}
+
+	repo.getUserErr = fmt.Errorf("get user: %w", errors.New("Error 1054: Unknown column 'time_zone'"))
+	_, err = svc.GetUser(ctx, "user1")
+	if !errors.Is(err, ErrInternalError) || !strings.Contains(err.Error(), "Unknown column") {
+		t.Errorf("Expected error %v wrapping the cause when a query fails but got %v", ErrInternalError, err)
+	}
+}
+
+func TestScheduleConcurrentRequests(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}, {ID: "user3", Name: "Charlie"}} {
+		repo.addUsers(t, user)
+	}
+	svc := NewService(repo)
+
+	// Every request shares user1, so they all compete for the same slots
+	participantSets := [][]string{
+		{"user1", "user2"},
+		{"user2", "user1"},
+		{"user1", "user3"},
+		{"user1"},
+		{"user3", "user1", "user2"},
+		{"user1", "user2"},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(participantSets))
+	for i, participants := range participantSets {
+		wg.Add(1)
+		go func(i int, participants []string) {
+			defer wg.Done()
+			_, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+				ParticipantIDs:  participants,
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: tomorrowAt(9),
+					End:   tomorrowAt(17),
+				},
+				Title: fmt.Sprintf("Meeting %d", i),
+			})
+			errs <- err
+		}(i, participants)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	for userID, events := range repo.calendars(t, "user1", "user2", "user3") {
+		for i := range events {
+			for j := i + 1; j < len(events); j++ {
+				if events[i].StartTime.Before(events[j].EndTime) && events[j].StartTime.Before(events[i].EndTime) {
+					t.Errorf("Double booking for %s: %v-%v overlaps %v-%v", userID,
+						events[i].StartTime, events[i].EndTime, events[j].StartTime, events[j].EndTime)
+				}
+			}
+		}
+	}
+	if len(repo.userEvents(t, "user1")) != len(participantSets) {
+		t.Errorf("Expected %d meetings for user1, got %d", len(participantSets), len(repo.userEvents(t, "user1")))
+	}
+}
+
+func TestScheduleRetriesOnConflict(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	svc := NewService(repo)
+
+	// Another instance books user1 at 9 AM between our read and our write
+	repo.beforeBooking = func() {
+		err := repo.CreateEvent(context.Background(), &domain.CalendarEvent{
+			ID:        "external",
+			StartTime: tomorrowAt(9),
+			EndTime:   tomorrowAt(10),
+			UserID:    "user1",
 		})
+		if err != nil {
+			t.Errorf("Failed to add the concurrent booking: %v", err)
+		}
+	}
+
+	resp, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StartTime.Before(tomorrowAt(10)) {
+		t.Errorf("Expected the retry to avoid the concurrently booked slot, got %v", resp.StartTime)
+	}
+}
+
+func TestFindAvailability(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}} {
+		repo.addUsers(t, user)
+	}
+	svc := NewService(repo)
+
+	req := domain.AvailabilityRequest{
+		ScheduleRequest: domain.ScheduleRequest{
+			ParticipantIDs:  []string{"user1", "user2"},
+			DurationMinutes: 60,
+			TimeRange: domain.TimeRange{
+				Start: tomorrowAt(9),
+				End:   tomorrowAt(17),
+			},
+		},
+		Limit: 3,
+	}
+
+	resp, err := svc.FindAvailability(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Slots) != 3 {
+		t.Errorf("Expected 3 slots, got %d", len(resp.Slots))
+	}
+	if !resp.Slots[0].StartTime.Equal(tomorrowAt(9)) {
+		t.Errorf("Expected the best slot to start at 9 AM, got %v", resp.Slots[0].StartTime)
+	}
+	if len(resp.Slots[0].Breakdown) == 0 {
+		t.Error("Expected a per-criterion score breakdown")
+	}
+	if repo.storedMeetings() != 0 || len(repo.userEvents(t, "user1")) != 0 || len(repo.userEvents(t, "user2")) != 0 {
+		t.Error("Expected availability lookup not to book anything")
+	}
+
+	req.Limit = 0
+	resp, err = svc.FindAvailability(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Slots) != defaultAvailabilityLimit {
+		t.Errorf("Expected %d slots by default, got %d", defaultAvailabilityLimit, len(resp.Slots))
+	}
+
+	req.Limit = maxAvailabilityLimit + 1
+	if _, err := svc.FindAvailability(context.Background(), req); err == nil {
+		t.Error("Expected an error for a limit above the maximum")
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	repo.addUsers(t, &domain.User{ID: "user2", Name: "Bob"})
+	daily := domain.NewCalendarEvent("Standup", tomorrowAt(9), tomorrowAt(10), "user1")
+	daily.Recurrence = "FREQ=DAILY;COUNT=2"
+	repo.setEvents(t, "user1", *daily)
+	repo.setEvents(t, "user2",
+		*domain.NewCalendarEvent("Lunch", tomorrowAt(12), tomorrowAt(13), "user2"),
+	)
+	svc := NewService(repo)
+
+	req := domain.FreeBusyRequest{
+		ParticipantIDs: []string{"user1", "user2"},
+		TimeRange:      domain.TimeRange{Start: tomorrowAt(8), End: tomorrowAt(8).AddDate(0, 0, 1)},
+	}
+	resp, err := svc.FreeBusy(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Busy["user1"]) != 1 || !resp.Busy["user1"][0].Start.Equal(tomorrowAt(9)) {
+		t.Errorf("Expected user1 busy only for tomorrow's standup occurrence, got %v", resp.Busy["user1"])
+	}
+	if len(resp.Busy["user2"]) != 1 || !resp.Busy["user2"][0].End.Equal(tomorrowAt(13)) {
+		t.Errorf("Expected user2 busy over lunch, got %v", resp.Busy["user2"])
+	}
+	if len(resp.Free) != 3 || !resp.Free[1].Start.Equal(tomorrowAt(10)) || !resp.Free[1].End.Equal(tomorrowAt(12)) {
+		t.Errorf("Expected three shared free intervals around the events, got %v", resp.Free)
+	}
+
+	t.Run("Travel time is busy", func(t *testing.T) {
+		// The drive to an appointment just after the range starts within it
+		appointment := domain.NewCalendarEvent("Dentist", tomorrowAt(18), tomorrowAt(19), "user2")
+		appointment.TravelMinutes = 90
+		if err := repo.CreateEvent(context.Background(), appointment); err != nil {
+			t.Fatalf("Failed to add the appointment: %v", err)
+		}
+		defer repo.DeleteEvent(context.Background(), appointment.ID)
+
+		resp, err := svc.FreeBusy(context.Background(), domain.FreeBusyRequest{
+			ParticipantIDs: []string{"user2"},
+			TimeRange:      domain.TimeRange{Start: tomorrowAt(14), End: tomorrowAt(17)},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resp.Busy["user2"]) != 1 || !resp.Busy["user2"][0].Start.Equal(tomorrowAt(16).Add(30*time.Minute)) {
+			t.Errorf("Expected user2 busy from 16:30 for the travel time, got %v", resp.Busy["user2"])
+		}
+		if len(resp.Free) != 1 || !resp.Free[0].End.Equal(tomorrowAt(16).Add(30*time.Minute)) {
+			t.Errorf("Expected to be free until the travel time starts, got %v", resp.Free)
+		}
+	})
+
+	t.Run("Unknown participant", func(t *testing.T) {
+		req := req
+		req.ParticipantIDs = []string{"user1", "missing"}
+		_, err := svc.FreeBusy(context.Background(), req)
+		var missing *MissingUsersError
+		if !errors.As(err, &missing) || len(missing.IDs) != 1 || missing.IDs[0] != "missing" {
+			t.Errorf("Expected the missing participant to be reported, got %v", err)
+		}
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		for name, invalid := range map[string]domain.FreeBusyRequest{
+			"no participants": {TimeRange: req.TimeRange},
+			"duplicates":      {ParticipantIDs: []string{"user1", "user1"}, TimeRange: req.TimeRange},
+			"empty range":     {ParticipantIDs: req.ParticipantIDs, TimeRange: domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(9)}},
+			"too long":        {ParticipantIDs: req.ParticipantIDs, TimeRange: domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(9).AddDate(2, 0, 0)}},
+		} {
+			if _, err := svc.FreeBusy(context.Background(), invalid); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
+
+func TestBuffersAndTravelTime(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice", BufferBeforeMinutes: 30})
+	repo.addUsers(t, &domain.User{ID: "user2", Name: "Bob"})
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	// Ends before the search window, but user1's buffer still reaches into it
+	early := tomorrowAt(8)
+	if _, err := svc.CreateEvent(ctx, "user1", domain.EventRequest{StartTime: early, EndTime: early.Add(50 * time.Minute)}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	offsite, err := svc.CreateEvent(ctx, "user2", domain.EventRequest{
+		Title:         "Client visit",
+		StartTime:     tomorrowAt(11),
+		EndTime:       tomorrowAt(12),
+		TravelMinutes: 45,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if offsite.TravelMinutes != 45 {
+		t.Errorf("Expected the travel time to be stored, got %d", offsite.TravelMinutes)
+	}
+
+	req := domain.ScheduleRequest{
+		ParticipantIDs:      []string{"user1", "user2"},
+		DurationMinutes:     30,
+		TimeRange:           domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+		Strategy:            algorithm.StrategyEarliest,
+		BufferAfterMinutes:  15,
+		BufferBeforeMinutes: 10,
+	}
+	availability, err := svc.FindAvailability(ctx, domain.AvailabilityRequest{ScheduleRequest: req, Limit: maxAvailabilityLimit})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, slot := range availability.Slots {
+		if slot.StartTime.Before(tomorrowAt(8).Add(80 * time.Minute)) {
+			t.Errorf("Expected user1's buffer after the early event to be kept free, got %v", slot.StartTime)
+		}
+		if slot.EndTime.After(tomorrowAt(10)) && slot.StartTime.Before(tomorrowAt(12).Add(55*time.Minute)) {
+			t.Errorf("Expected no slot within the travel time and buffers around the visit, got %v", slot.StartTime)
+		}
+	}
+
+	resp, err := svc.Schedule(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.StartTime.Equal(tomorrowAt(9).Add(30 * time.Minute)) {
+		t.Errorf("Expected the earliest slot with breathing room at 9:30, got %v", resp.StartTime)
+	}
+
+	t.Run("Meeting events keep their buffers", func(t *testing.T) {
+		meeting, err := svc.GetMeeting(ctx, resp.MeetingID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if meeting.BufferBeforeMinutes != 10 || meeting.BufferAfterMinutes != 15 {
+			t.Errorf("Expected the meeting to keep its buffers, got %d and %d", meeting.BufferBeforeMinutes, meeting.BufferAfterMinutes)
+		}
+
+		freeBusy, err := svc.FreeBusy(ctx, domain.FreeBusyRequest{
+			ParticipantIDs: []string{"user2"},
+			TimeRange:      domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(10).Add(15 * time.Minute)},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		busy := freeBusy.Busy["user2"]
+		if len(busy) != 1 || !busy[0].Start.Equal(tomorrowAt(9).Add(20*time.Minute)) || !busy[0].End.Equal(tomorrowAt(10).Add(15*time.Minute)) {
+			t.Errorf("Expected user2 to be busy from 9:20 to 10:15, got %v", busy)
+		}
+
+		_, err = svc.CreateEvent(ctx, "user2", domain.EventRequest{StartTime: tomorrowAt(10), EndTime: tomorrowAt(10).Add(10 * time.Minute)})
+		if err != ErrEventConflict {
+			t.Errorf("Expected an event during the meeting's buffer to conflict, got %v", err)
+		}
+	})
+
+	t.Run("Events respect travel time", func(t *testing.T) {
+		_, err := svc.CreateEvent(ctx, "user2", domain.EventRequest{StartTime: tomorrowAt(12), EndTime: tomorrowAt(13)})
+		if err != ErrEventConflict {
+			t.Errorf("Expected an event during the travel time to conflict, got %v", err)
+		}
+		_, err = svc.CreateEvent(ctx, "user2", domain.EventRequest{StartTime: tomorrowAt(13), EndTime: tomorrowAt(14), TravelMinutes: 45})
+		if err != nil {
+			t.Errorf("Expected travel times to be allowed to overlap, got %v", err)
+		}
+		_, err = svc.CreateEvent(ctx, "user2", domain.EventRequest{StartTime: tomorrowAt(15), EndTime: tomorrowAt(16), TravelMinutes: domain.MaxTravelMinutes + 1})
+		if err == nil {
+			t.Error("Expected an error for excessive travel time")
+		}
+	})
+
+	t.Run("Invalid buffers", func(t *testing.T) {
+		invalid := req
+		invalid.BufferBeforeMinutes = domain.MaxBufferMinutes + 1
+		if _, err := svc.Schedule(ctx, invalid); err == nil {
+			t.Error("Expected an error for an excessive request buffer")
+		}
+		negative := -5
+		if _, err := svc.UpdateUser(ctx, "user1", domain.UpdateUserRequest{BufferAfterMinutes: &negative}); err == nil {
+			t.Error("Expected an error for a negative user buffer")
+		}
+	})
+}
+
+func TestScheduleWithResources(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	repo.addUsers(t, &domain.User{ID: "user2", Name: "Bob"})
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	create := func(req domain.CreateResourceRequest) *domain.Resource {
+		resource, err := svc.CreateResource(ctx, req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return resource
+	}
+	large := create(domain.CreateResourceRequest{Name: "Auditorium", Capacity: 40, Building: "HQ", Attributes: []string{"projector"}})
+	small := create(domain.CreateResourceRequest{Name: "Huddle", Capacity: 6, Building: "HQ", Attributes: []string{"Projector", "whiteboard"}})
+	create(domain.CreateResourceRequest{Name: "Annex room", Capacity: 10, Building: "Annex", Attributes: []string{"projector"}})
+	if small.Kind != domain.ResourceRoom {
+		t.Errorf("Expected resources to be rooms by default, got %q", small.Kind)
+	}
+
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(10)},
+		Resources: []domain.ResourceRequirement{
+			{Kind: domain.ResourceRoom, MinCapacity: 4, Building: "hq", Attributes: []string{"projector"}},
+		},
+	}
+	resp, err := svc.Schedule(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.ResourceIDs) != 1 || resp.ResourceIDs[0] != small.ID {
+		t.Errorf("Expected the smallest matching room to be booked, got %v", resp.ResourceIDs)
+	}
+	calendar, err := svc.GetResourceCalendar(ctx, small.ID, tomorrowAt(0), tomorrowAt(24))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calendar) != 1 || calendar[0].MeetingID == nil || *calendar[0].MeetingID != resp.MeetingID {
+		t.Errorf("Expected the room's calendar to hold the meeting, got %+v", calendar)
+	}
+	meeting, err := svc.GetMeeting(ctx, resp.MeetingID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(meeting.ParticipantIDs) != 2 || len(meeting.ResourceIDs) != 1 {
+		t.Errorf("Expected the room to be kept apart from the participants, got %v and %v", meeting.ParticipantIDs, meeting.ResourceIDs)
+	}
+
+	t.Run("Booked rooms are avoided", func(t *testing.T) {
+		repo.addUsers(t, &domain.User{ID: "user3", Name: "Carol"})
+		other := req
+		other.ParticipantIDs = []string{"user3"}
+		resp, err := svc.Schedule(ctx, other)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resp.ResourceIDs) != 1 || resp.ResourceIDs[0] != large.ID {
+			t.Errorf("Expected the next matching room while the huddle room is booked, got %v", resp.ResourceIDs)
+		}
+
+		repo.addUsers(t, &domain.User{ID: "user4", Name: "Dave"})
+		other.ParticipantIDs = []string{"user4"}
+		if _, err := svc.Schedule(ctx, other); err != ErrNoAvailableSlot {
+			t.Errorf("Expected no slot with every matching room booked, got %v", err)
+		}
+	})
+
+	t.Run("Reschedule keeps the room", func(t *testing.T) {
+		moved, err := svc.RescheduleMeeting(ctx, resp.MeetingID, domain.RescheduleRequest{
+			TimeRange: domain.TimeRange{Start: tomorrowAt(14), End: tomorrowAt(15)},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(moved.ResourceIDs) != 1 || moved.ResourceIDs[0] != small.ID {
+			t.Errorf("Expected the meeting to keep its room, got %v", moved.ResourceIDs)
+		}
+		calendar, err := svc.GetResourceCalendar(ctx, small.ID, tomorrowAt(0), tomorrowAt(24))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(calendar) != 1 || !calendar[0].StartTime.Equal(tomorrowAt(14)) {
+			t.Errorf("Expected the room booking to move with the meeting, got %+v", calendar)
+		}
+	})
+
+	t.Run("Unmatched requirement", func(t *testing.T) {
+		unmatched := req
+		unmatched.Resources = []domain.ResourceRequirement{{MinCapacity: 100}}
+		if _, err := svc.Schedule(ctx, unmatched); err != ErrNoMatchingResource {
+			t.Errorf("Expected ErrNoMatchingResource, got %v", err)
+		}
+		if _, err := svc.GetResource(ctx, "missing"); err != ErrResourceNotFound {
+			t.Errorf("Expected ErrResourceNotFound, got %v", err)
+		}
+		if _, err := svc.CreateResource(ctx, domain.CreateResourceRequest{Name: "Cart", Kind: "vehicle"}); err == nil {
+			t.Error("Expected an error for an unknown resource kind")
+		}
+	})
+}
+
+func TestScoringStrategy(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+
+	registry := algorithm.NewRegistry()
+	registry.Register("late", algorithm.WeightedScorer{Weights: algorithm.Weights{algorithm.CriterionAfternoon: 1}})
+	svc := NewService(repo, WithScorers(registry))
+
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+		Strategy: "late",
+	}
+
+	resp, err := svc.Schedule(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.StartTime.Equal(tomorrowAt(12)) {
+		t.Errorf("Expected the custom strategy to pick noon, got %v", resp.StartTime)
+	}
+
+	if resp.Explanation != nil {
+		t.Error("Expected no explanation unless asked for")
+	}
+
+	req.Explain = true
+	req.TimeRange.Start = tomorrowAt(13)
+	resp, err = svc.Schedule(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Explanation == nil || resp.Explanation.Strategy != "late" || len(resp.Explanation.Breakdown) != 1 {
+		t.Fatalf("Expected an explanation of the custom strategy, got %+v", resp.Explanation)
+	}
+	if participants := resp.Explanation.Breakdown[0].Participants; len(participants) != 1 || participants[0].UserID != "user1" {
+		t.Errorf("Expected a per-participant score for user1, got %v", participants)
+	}
+
+	req.Strategy = "no-such-strategy"
+	if _, err := svc.Schedule(context.Background(), req); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestOptionalParticipants(t *testing.T) {
+	repo := NewMockRepository()
+	for _, id := range []string{"user1", "user2", "user3"} {
+		repo.addUsers(t, &domain.User{ID: id, Name: id})
+	}
+	repo.setEvents(t, "user3",
+		*domain.NewCalendarEvent("Busy", tomorrowAt(9), tomorrowAt(17), "user3"),
+	)
+	svc := NewService(repo)
+
+	req := domain.ScheduleRequest{
+		ParticipantIDs:         []string{"user1"},
+		OptionalParticipantIDs: []string{"user2", "user3"},
+		DurationMinutes:        60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+	}
+
+	resp, err := svc.Schedule(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.ParticipantIDs) != 2 || resp.ParticipantIDs[0] != "user1" || resp.ParticipantIDs[1] != "user2" {
+		t.Errorf("Expected user1 and user2 to be booked, got %v", resp.ParticipantIDs)
+	}
+	if len(resp.UnavailableParticipantIDs) != 1 || resp.UnavailableParticipantIDs[0] != "user3" {
+		t.Errorf("Expected user3 to be reported as unavailable, got %v", resp.UnavailableParticipantIDs)
+	}
+	if len(repo.userEvents(t, "user3")) != 1 {
+		t.Error("Expected no meeting event for the unavailable participant")
+	}
+
+	req.MinAttendees = 3
+	req.TimeRange.Start = tomorrowAt(11)
+	if _, err := svc.Schedule(context.Background(), req); err != ErrNoAvailableSlot {
+		t.Errorf("Expected ErrNoAvailableSlot when the quorum cannot be met, got %v", err)
+	}
+
+	req.MinAttendees = 4
+	if _, err := svc.Schedule(context.Background(), req); err == nil {
+		t.Error("Expected an error for a quorum larger than the participant list")
+	}
+
+	req.MinAttendees = 0
+	req.OptionalParticipantIDs = []string{"user1"}
+	if _, err := svc.Schedule(context.Background(), req); err == nil {
+		t.Error("Expected an error for a participant who is both required and optional")
+	}
+}
+
+func TestRecurringMeeting(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	repo.addUsers(t, &domain.User{ID: "user2", Name: "Bob"})
+	nextWeek := tomorrowAt(9).AddDate(0, 0, 7)
+	repo.setEvents(t, "user2",
+		*domain.NewCalendarEvent("Dentist", nextWeek, nextWeek.Add(time.Hour), "user2"),
+	)
+	svc := NewService(repo)
+
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(12),
+		},
+		Recurrence: "FREQ=WEEKLY;COUNT=4",
+	}
+
+	resp, err := svc.Schedule(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.StartTime.Equal(tomorrowAt(10)) || resp.Recurrence != "FREQ=WEEKLY;COUNT=4" {
+		t.Errorf("Expected a weekly series at 10 AM avoiding next week's conflict, got %v %q", resp.StartTime, resp.Recurrence)
+	}
+
+	calendar, err := svc.GetUserCalendar(context.Background(), "user1", tomorrowAt(0), tomorrowAt(0).AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calendar) != 4 {
+		t.Fatalf("Expected 4 occurrences on the calendar, got %d", len(calendar))
+	}
+	for i, event := range calendar {
+		if !event.StartTime.Equal(tomorrowAt(10).AddDate(0, 0, 7*i)) || event.RecurrenceID == nil {
+			t.Errorf("Expected occurrence %d a week after the previous one, got %v", i, event.StartTime)
+		}
+	}
+
+	t.Run("Export keeps the series", func(t *testing.T) {
+		exported, err := svc.ExportCalendar(context.Background(), "user1", time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(exported) != 1 || exported[0].Recurrence == "" || exported[0].RecurrenceID != nil {
+			t.Errorf("Expected the series as a single unexpanded event, got %+v", exported)
+		}
+
+		later := tomorrowAt(0).AddDate(0, 2, 0)
+		exported, err = svc.ExportCalendar(context.Background(), "user1", later, later.AddDate(0, 1, 0))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(exported) != 0 {
+			t.Errorf("Expected no events after the series has ended, got %+v", exported)
+		}
+	})
+
+	t.Run("Series blocks later bookings", func(t *testing.T) {
+		_, err := svc.CreateEvent(context.Background(), "user1", domain.EventRequest{
+			StartTime: tomorrowAt(10).AddDate(0, 0, 14),
+			EndTime:   tomorrowAt(11).AddDate(0, 0, 14),
+		})
+		if err != ErrEventConflict {
+			t.Errorf("Expected ErrEventConflict for an event on a later occurrence, got %v", err)
+		}
+	})
+
+	t.Run("Cancel one occurrence", func(t *testing.T) {
+		second := tomorrowAt(10).AddDate(0, 0, 7)
+		if err := svc.CancelOccurrence(context.Background(), resp.MeetingID, second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		calendar, _ := svc.GetUserCalendar(context.Background(), "user2", tomorrowAt(0), tomorrowAt(0).AddDate(0, 1, 0))
+		for _, event := range calendar {
+			if event.StartTime.Equal(second) {
+				t.Error("Expected the cancelled occurrence to be gone")
+			}
+		}
+		if err := svc.CancelOccurrence(context.Background(), resp.MeetingID, second); err != ErrOccurrenceNotFound {
+			t.Errorf("Expected ErrOccurrenceNotFound for an already cancelled occurrence, got %v", err)
+		}
+		if err := svc.CancelOccurrence(context.Background(), resp.MeetingID, second.Add(time.Hour)); err != ErrOccurrenceNotFound {
+			t.Errorf("Expected ErrOccurrenceNotFound for a time that is not an occurrence, got %v", err)
+		}
+	})
+
+	t.Run("Threshold", func(t *testing.T) {
+		req := req
+		req.ParticipantIDs = []string{"user2"}
+		req.TimeRange.End = tomorrowAt(10)
+		if _, err := svc.Schedule(context.Background(), req); err != ErrNoAvailableSlot {
+			t.Errorf("Expected ErrNoAvailableSlot when an occurrence is busy, got %v", err)
+		}
+
+		req.OccurrenceThreshold = 0.75
+		resp, err := svc.Schedule(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resp.SkippedOccurrences) != 1 || !resp.SkippedOccurrences[0].Equal(nextWeek) {
+			t.Errorf("Expected next week's occurrence to be skipped, got %v", resp.SkippedOccurrences)
+		}
+	})
+
+	t.Run("Series repeats in the organizer's time zone", func(t *testing.T) {
+		repo.addUsers(t, &domain.User{ID: "user3", Name: "Carol", TimeZone: "Europe/Berlin"})
+		// 9 AM in Berlin on the Monday before the switch to summer time,
+		// requested as a fixed offset
+		year := time.Now().Year() + 1
+		lastSunday := 31 - int(time.Date(year, 3, 31, 0, 0, 0, 0, time.UTC).Weekday())
+		start := time.Date(year, 3, lastSunday-6, 9, 0, 0, 0, time.FixedZone("", 3600))
+		req := domain.ScheduleRequest{
+			ParticipantIDs:  []string{"user3"},
+			DurationMinutes: 60,
+			TimeRange:       domain.TimeRange{Start: start, End: start.Add(time.Hour)},
+			Recurrence:      "FREQ=WEEKLY;COUNT=2",
+		}
+		resp, err := svc.Schedule(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		meeting, err := svc.GetMeeting(context.Background(), resp.MeetingID)
+		if err != nil || meeting.TimeZone != "Europe/Berlin" {
+			t.Fatalf("Expected the series to be stored in Europe/Berlin, got %+v (%v)", meeting, err)
+		}
+
+		calendar, err := svc.GetUserCalendar(context.Background(), "user3", start, start.AddDate(0, 0, 14))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		second := start.AddDate(0, 0, 7).Add(-time.Hour)
+		if len(calendar) != 2 || !calendar[1].StartTime.Equal(second) {
+			t.Errorf("Expected the second occurrence at 9 AM summer time (%v), got %+v", second, calendar)
+		}
+	})
+
+	t.Run("Invalid rule", func(t *testing.T) {
+		req := req
+		req.Recurrence = "FREQ=FORTNIGHTLY"
+		if _, err := svc.Schedule(context.Background(), req); err == nil {
+			t.Error("Expected an error for an invalid recurrence rule")
+		}
+	})
+}
+
+func TestImportCalendar(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	svc := NewService(repo)
+
+	day := tomorrowAt(0)
+	stamp := func(t time.Time) string { return t.UTC().Format("20060102T150405Z") }
+	data := []byte(strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"DTSTART:" + stamp(day.Add(9*time.Hour)),
+		"DTEND:" + stamp(day.Add(10*time.Hour)),
+		"SUMMARY:Standup",
+		"RRULE:FREQ=DAILY;COUNT=3",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"RECURRENCE-ID:" + stamp(day.Add(33*time.Hour)),
+		"DTSTART:" + stamp(day.Add(35*time.Hour)),
+		"DTEND:" + stamp(day.Add(36*time.Hour)),
+		"SUMMARY:Standup (moved)",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:offsite@example.com",
+		"DTSTART;VALUE=DATE:" + day.AddDate(0, 0, 2).Format("20060102"),
+		"SUMMARY:Offsite",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:reminder@example.com",
+		"DTSTART:" + stamp(day.Add(12*time.Hour)),
+		"DTEND:" + stamp(day.Add(13*time.Hour)),
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:meeting-1@meeting-scheduler",
+		"DTSTART:" + stamp(day.Add(14*time.Hour)),
+		"DTEND:" + stamp(day.Add(15*time.Hour)),
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n"))
+
+	result, err := svc.ImportCalendar(context.Background(), "user1", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Created != 3 || result.Updated != 0 || len(result.Skipped) != 2 {
+		t.Errorf("Expected 3 created and 2 skipped events, got %+v", result)
+	}
+
+	calendar, err := svc.GetUserCalendar(context.Background(), "user1", day, day.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var starts []time.Time
+	for _, event := range calendar {
+		starts = append(starts, event.StartTime)
+	}
+	expected := []time.Time{day.Add(9 * time.Hour), day.Add(35 * time.Hour), day.AddDate(0, 0, 2), day.Add(57 * time.Hour)}
+	if len(starts) != len(expected) {
+		t.Fatalf("Expected %d events on the calendar, got %v", len(expected), starts)
+	}
+	for i := range expected {
+		if !starts[i].Equal(expected[i]) {
+			t.Errorf("Expected event %d at %v, got %v", i, expected[i], starts[i])
+		}
+	}
+
+	t.Run("Imported events block scheduling", func(t *testing.T) {
+		resp, err := svc.FindAvailability(context.Background(), domain.AvailabilityRequest{
+			ScheduleRequest: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1"},
+				DurationMinutes: 60,
+				TimeRange:       domain.TimeRange{Start: day.Add(9 * time.Hour), End: day.Add(11 * time.Hour)},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, slot := range resp.Slots {
+			if slot.StartTime.Before(day.Add(10 * time.Hour)) {
+				t.Errorf("Expected no slot during the imported standup, got %v", slot.StartTime)
+			}
+		}
+	})
+
+	t.Run("Re-import updates by UID", func(t *testing.T) {
+		result, err := svc.ImportCalendar(context.Background(), "user1", data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Created != 0 || result.Updated != 3 {
+			t.Errorf("Expected all 3 events to be updated, got %+v", result)
+		}
+		if len(repo.userEvents(t, "user1")) != 3 {
+			t.Errorf("Expected no duplicate events, got %d", len(repo.userEvents(t, "user1")))
+		}
+	})
+
+	t.Run("Re-import as cancelled removes the event", func(t *testing.T) {
+		cancelled := []byte(strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"BEGIN:VEVENT",
+			"UID:offsite@example.com",
+			"DTSTART;VALUE=DATE:" + day.AddDate(0, 0, 2).Format("20060102"),
+			"SUMMARY:Offsite",
+			"STATUS:CANCELLED",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:lunch@example.com",
+			"DTSTART:" + stamp(day.Add(12*time.Hour)),
+			"DTEND:" + stamp(day.Add(13*time.Hour)),
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:lunch@example.com",
+			"DTSTART:" + stamp(day.Add(12*time.Hour)),
+			"DTEND:" + stamp(day.Add(13*time.Hour)),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n"))
+
+		result, err := svc.ImportCalendar(context.Background(), "user1", cancelled)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Created != 0 || result.Removed != 1 {
+			t.Errorf("Expected the offsite to be removed and nothing created, got %+v", result)
+		}
+		for _, event := range repo.userEvents(t, "user1") {
+			if event.UID == "offsite@example.com" || event.UID == "lunch@example.com" {
+				t.Errorf("Expected %s not to be on the calendar", event.UID)
+			}
+		}
+
+		resp, err := svc.FindAvailability(context.Background(), domain.AvailabilityRequest{
+			ScheduleRequest: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1"},
+				DurationMinutes: 60,
+				TimeRange:       domain.TimeRange{Start: day.AddDate(0, 0, 2).Add(12 * time.Hour), End: day.AddDate(0, 0, 2).Add(13 * time.Hour)},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resp.Slots) == 0 {
+			t.Error("Expected the cancelled offsite to stop blocking its day")
+		}
+	})
+
+	t.Run("Unknown time zone skips only that event", func(t *testing.T) {
+		later := day.AddDate(0, 0, 5)
+		data := []byte(strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"BEGIN:VEVENT",
+			"UID:mars@example.com",
+			"DTSTART;TZID=Mars/Olympus:" + later.Format("20060102") + "T090000",
+			"DTEND;TZID=Mars/Olympus:" + later.Format("20060102") + "T100000",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:earth@example.com",
+			"DTSTART:" + stamp(later.Add(11*time.Hour)),
+			"DTEND:" + stamp(later.Add(12*time.Hour)),
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n"))
+		result, err := svc.ImportCalendar(context.Background(), "user1", data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Created != 1 || len(result.Skipped) != 1 || result.Skipped[0].UID != "mars@example.com" ||
+			!strings.Contains(result.Skipped[0].Reason, "unknown time zone") {
+			t.Errorf("Expected the event in an unknown time zone to be skipped, got %+v", result)
+		}
+	})
+
+	t.Run("Invalid data", func(t *testing.T) {
+		if _, err := svc.ImportCalendar(context.Background(), "user1", []byte("not a calendar")); err == nil {
+			t.Error("Expected an error for invalid iCalendar data")
+		}
+		if _, err := svc.ImportCalendar(context.Background(), "missing", data); err != ErrUserNotFound {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
+
+func TestCancelMeeting(t *testing.T) {
+	repo := NewMockRepository()
+	repo.addUsers(t, &domain.User{ID: "user1", Name: "Alice"})
+	svc := NewService(repo)
+
+	resp, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := svc.CancelMeeting(context.Background(), resp.MeetingID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(repo.userEvents(t, "user1")) != 0 {
+		t.Errorf("Expected the meeting to be removed from the calendar, got %d events", len(repo.userEvents(t, "user1")))
+	}
+	if _, err := svc.GetMeeting(context.Background(), resp.MeetingID); err != ErrMeetingNotFound {
+		t.Errorf("Expected error %v but got %v", ErrMeetingNotFound, err)
+	}
+	if err := svc.CancelMeeting(context.Background(), resp.MeetingID); err != ErrMeetingNotFound {
+		t.Errorf("Expected error %v but got %v", ErrMeetingNotFound, err)
+	}
+}
+
+func TestRescheduleMeeting(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}} {
+		repo.addUsers(t, user)
+	}
+	svc := NewService(repo)
+
+	resp, err := svc.Schedule(context.Background(), domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9),
+			End:   tomorrowAt(17),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The new window overlaps the meeting's current slot, which must not
+	// count as a conflict with itself.
+	moved, err := svc.RescheduleMeeting(context.Background(), resp.MeetingID, domain.RescheduleRequest{
+		TimeRange: domain.TimeRange{
+			Start: tomorrowAt(9).Add(30 * time.Minute),
+			End:   tomorrowAt(17),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedStart := tomorrowAt(9).Add(30 * time.Minute)
+	if !moved.StartTime.Equal(expectedStart) {
+		t.Errorf("Expected start time %v, got %v", expectedStart, moved.StartTime)
+	}
+	if moved.EndTime.Sub(moved.StartTime) != time.Hour {
+		t.Errorf("Expected the original 60 minute duration, got %v", moved.EndTime.Sub(moved.StartTime))
+	}
+	for userID, events := range repo.calendars(t, "user1", "user2") {
+		if len(events) != 1 || !events[0].StartTime.Equal(expectedStart) {
+			t.Errorf("Expected %s's event to move to %v, got %+v", userID, expectedStart, events)
+		}
+	}
+
+	_, err = svc.RescheduleMeeting(context.Background(), "nonexistent", domain.RescheduleRequest{
+		TimeRange: domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	if err != ErrMeetingNotFound {
+		t.Errorf("Expected error %v but got %v", ErrMeetingNotFound, err)
+	}
+}
+
+func TestUserManagement(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	invalid := []domain.CreateUserRequest{
+		{Name: ""},
+		{Name: "Dana", TimeZone: "Mars/Olympus_Mons"},
+		{Name: "Dana", WorkdayStart: 17, WorkdayEnd: 9},
+		{Name: "Dana", WorkdayStart: 9, WorkdayEnd: 25},
+	}
+	for _, req := range invalid {
+		if _, err := svc.CreateUser(ctx, req); err == nil {
+			t.Errorf("Expected an error creating %+v", req)
+		}
+	}
+
+	alice, err := svc.CreateUser(ctx, domain.CreateUserRequest{Name: "Alice", TimeZone: "Europe/Berlin"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bob, err := svc.CreateUser(ctx, domain.CreateUserRequest{Name: "Bob", WorkdayStart: 8, WorkdayEnd: 16})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	users, err := svc.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(users))
+	}
+
+	name, tz := "Alicia", "Asia/Tokyo"
+	updated, err := svc.UpdateUser(ctx, alice.ID, domain.UpdateUserRequest{Name: &name, TimeZone: &tz})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updated.Name != name || updated.TimeZone != tz {
+		t.Errorf("Expected name %q and time zone %q, got %q and %q", name, tz, updated.Name, updated.TimeZone)
+	}
+	if start, end := updated.WorkingHours(); start != domain.DefaultWorkdayStart || end != domain.DefaultWorkdayEnd {
+		t.Errorf("Expected untouched default working hours, got %d-%d", start, end)
+	}
+	badStart := 20
+	if _, err := svc.UpdateUser(ctx, bob.ID, domain.UpdateUserRequest{WorkdayStart: &badStart}); err == nil {
+		t.Error("Expected an error for working hours ending before they start")
+	}
+	if _, err := svc.UpdateUser(ctx, "nonexistent", domain.UpdateUserRequest{Name: &name}); err != ErrUserNotFound {
+		t.Errorf("Expected error %v but got %v", ErrUserNotFound, err)
+	}
+
+	// Alice organizes a meeting with Bob and a solo meeting
+	shared, err := svc.Schedule(ctx, domain.ScheduleRequest{
+		ParticipantIDs:  []string{alice.ID, bob.ID},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	solo, err := svc.Schedule(ctx, domain.ScheduleRequest{
+		ParticipantIDs:  []string{alice.ID},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := svc.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := svc.GetUser(ctx, alice.ID); err != ErrUserNotFound {
+		t.Errorf("Expected error %v but got %v", ErrUserNotFound, err)
+	}
+	if _, err := svc.GetMeeting(ctx, solo.MeetingID); err != ErrMeetingNotFound {
+		t.Errorf("Expected the solo meeting to be deleted, got %v", err)
+	}
+	meeting, err := svc.GetMeeting(ctx, shared.MeetingID)
+	if err != nil {
+		t.Fatalf("Expected the shared meeting to survive, got %v", err)
+	}
+	if len(meeting.ParticipantIDs) != 1 || meeting.ParticipantIDs[0] != bob.ID {
+		t.Errorf("Expected only Bob to remain in the meeting, got %v", meeting.ParticipantIDs)
+	}
+	if meeting.OrganizerID != bob.ID {
+		t.Errorf("Expected Bob to become the organizer, got %q", meeting.OrganizerID)
+	}
+	if err := svc.DeleteUser(ctx, alice.ID); err != ErrUserNotFound {
+		t.Errorf("Expected error %v but got %v", ErrUserNotFound, err)
+	}
+}
+
+func TestEventManagement(t *testing.T) {
+	repo := NewMockRepository()
+	for _, user := range []*domain.User{{ID: "user1", Name: "Alice"}, {ID: "user2", Name: "Bob"}} {
+		repo.addUsers(t, user)
+	}
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	focus, err := svc.CreateEvent(ctx, "user1", domain.EventRequest{
+		Title:     "Focus time",
+		StartTime: tomorrowAt(9),
+		EndTime:   tomorrowAt(11),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if focus.MeetingID != nil {
+		t.Error("Expected a manual event not to belong to a meeting")
+	}
+
+	invalid := []domain.EventRequest{
+		{StartTime: tomorrowAt(9)},
+		{StartTime: tomorrowAt(11), EndTime: tomorrowAt(10)},
+	}
+	for _, req := range invalid {
+		if _, err := svc.CreateEvent(ctx, "user1", req); err == nil {
+			t.Errorf("Expected an error creating %+v", req)
+		}
+	}
+	if _, err := svc.CreateEvent(ctx, "nonexistent", domain.EventRequest{StartTime: tomorrowAt(9), EndTime: tomorrowAt(10)}); err != ErrUserNotFound {
+		t.Errorf("Expected error %v but got %v", ErrUserNotFound, err)
+	}
+	_, err = svc.CreateEvent(ctx, "user1", domain.EventRequest{StartTime: tomorrowAt(8), EndTime: tomorrowAt(12)})
+	if err != ErrEventConflict {
+		t.Errorf("Expected error %v but got %v", ErrEventConflict, err)
+	}
+
+	// Scheduling must work around the blocked time
+	meeting, err := svc.Schedule(ctx, domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1", "user2"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: tomorrowAt(9), End: tomorrowAt(17)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meeting.StartTime.Before(tomorrowAt(11)) {
+		t.Errorf("Expected the meeting to be booked after the focus time, got %v", meeting.StartTime)
+	}
+
+	moved, err := svc.UpdateEvent(ctx, "user1", focus.ID, domain.EventRequest{
+		Title:     "Deep work",
+		StartTime: tomorrowAt(14),
+		EndTime:   tomorrowAt(15),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if moved.Title != "Deep work" || !moved.StartTime.Equal(tomorrowAt(14)) {
+		t.Errorf("Expected the event to be updated, got %+v", moved)
+	}
+	_, err = svc.UpdateEvent(ctx, "user1", focus.ID, domain.EventRequest{StartTime: meeting.StartTime, EndTime: meeting.EndTime})
+	if err != ErrEventConflict {
+		t.Errorf("Expected error %v but got %v", ErrEventConflict, err)
+	}
+	_, err = svc.UpdateEvent(ctx, "user2", focus.ID, domain.EventRequest{StartTime: tomorrowAt(14), EndTime: tomorrowAt(15)})
+	if err != ErrEventNotFound {
+		t.Errorf("Expected error %v but got %v", ErrEventNotFound, err)
+	}
+
+	meetingEvent := repo.userEvents(t, "user2")[0]
+	if err := svc.DeleteEvent(ctx, "user2", meetingEvent.ID); err != ErrMeetingEvent {
+		t.Errorf("Expected error %v but got %v", ErrMeetingEvent, err)
+	}
+
+	if err := svc.DeleteEvent(ctx, "user1", focus.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := svc.DeleteEvent(ctx, "user1", focus.ID); err != ErrEventNotFound {
+		t.Errorf("Expected error %v but got %v", ErrEventNotFound, err)
 	}
 }
 
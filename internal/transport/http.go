@@ -3,7 +3,12 @@ package transport
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	kitendpoint "github.com/go-kit/kit/endpoint"
@@ -13,8 +18,12 @@ import (
 	"github.com/meeting-scheduler/internal/domain"
 	"github.com/meeting-scheduler/internal/endpoint"
 	"github.com/meeting-scheduler/internal/service"
+	"github.com/meeting-scheduler/pkg/ical"
 )
 
+// maxImportBytes bounds the size of an iCalendar file accepted for import
+const maxImportBytes = 10 << 20
+
 // NewHTTPHandler returns an HTTP handler for the scheduler service
 func NewHTTPHandler(endpoints endpoint.Endpoints, logger log.Logger) http.Handler {
 	r := mux.NewRouter()
@@ -26,9 +35,149 @@ func NewHTTPHandler(endpoints endpoint.Endpoints, logger log.Logger) http.Handle
 
 	r.Methods("POST").Path("/schedule").HandlerFunc(scheduleHandler(endpoints.Schedule, logger, options))
 
+	r.Methods("POST").Path("/availability").Handler(httptransport.NewServer(
+		endpoints.FindAvailability,
+		decodeAvailabilityRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/freebusy").Handler(httptransport.NewServer(
+		endpoints.FreeBusy,
+		decodeFreeBusyRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/users").Handler(httptransport.NewServer(
+		endpoints.CreateUser,
+		decodeCreateUserRequest,
+		encodeCreatedResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/users").Handler(httptransport.NewServer(
+		endpoints.ListUsers,
+		httptransport.NopRequestDecoder,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/users/{userId}").Handler(httptransport.NewServer(
+		endpoints.GetUser,
+		decodeUserRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("PATCH").Path("/users/{userId}").Handler(httptransport.NewServer(
+		endpoints.UpdateUser,
+		decodeUpdateUserRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/users/{userId}").Handler(httptransport.NewServer(
+		endpoints.DeleteUser,
+		decodeUserRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/resources").Handler(httptransport.NewServer(
+		endpoints.CreateResource,
+		decodeCreateResourceRequest,
+		encodeCreatedResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/resources").Handler(httptransport.NewServer(
+		endpoints.ListResources,
+		httptransport.NopRequestDecoder,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/resources/{resourceId}").Handler(httptransport.NewServer(
+		endpoints.GetResource,
+		decodeResourceRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/resources/{resourceId}/calendar").Handler(httptransport.NewServer(
+		endpoints.GetResourceCalendar,
+		decodeGetResourceCalendarRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/users/{userId}/events").Handler(httptransport.NewServer(
+		endpoints.CreateEvent,
+		decodeEventRequest,
+		encodeCreatedResponse,
+		options...,
+	))
+
+	r.Methods("PUT").Path("/users/{userId}/events/{eventId}").Handler(httptransport.NewServer(
+		endpoints.UpdateEvent,
+		decodeEventRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/users/{userId}/events/{eventId}").Handler(httptransport.NewServer(
+		endpoints.DeleteEvent,
+		decodeDeleteEventRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
 	r.Methods("GET").Path("/users/{userId}/calendar").Handler(httptransport.NewServer(
 		endpoints.GetUserCalendar,
 		decodeGetUserCalendarRequest,
+		encodeCalendarResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/users/{userId}/calendar.ics").Handler(httptransport.NewServer(
+		endpoints.GetUserCalendar,
+		decodeICalendarRequest,
+		encodeCalendarResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/users/{userId}/calendar/import").Handler(httptransport.NewServer(
+		endpoints.ImportCalendar,
+		decodeImportCalendarRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/meetings/{meetingId}").Handler(httptransport.NewServer(
+		endpoints.GetMeeting,
+		decodeGetMeetingRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/meetings/{meetingId}").Handler(httptransport.NewServer(
+		endpoints.CancelMeeting,
+		decodeCancelMeetingRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/meetings/{meetingId}/occurrences/{occurrence}").Handler(httptransport.NewServer(
+		endpoints.CancelOccurrence,
+		decodeCancelOccurrenceRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/meetings/{meetingId}/reschedule").Handler(httptransport.NewServer(
+		endpoints.RescheduleMeeting,
+		decodeRescheduleMeetingRequest,
 		encodeResponse,
 		options...,
 	))
@@ -39,12 +188,113 @@ func NewHTTPHandler(endpoints endpoint.Endpoints, logger log.Logger) http.Handle
 func decodeScheduleRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req domain.ScheduleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		return nil, err
+		return nil, malformed(err)
+	}
+	if explainRequested(r) {
+		req.Explain = true
+	}
+	return req, nil
+}
+
+func decodeAvailabilityRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req domain.AvailabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
+	}
+	return req, nil
+}
+
+func decodeFreeBusyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req domain.FreeBusyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
+	}
+	return req, nil
+}
+
+func decodeCreateUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req domain.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
 	}
 	return req, nil
 }
 
-func decodeGetUserCalendarRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeCreateResourceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req domain.CreateResourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
+	}
+	return req, nil
+}
+
+func decodeResourceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoint.ResourceRequest{
+		ResourceID: mux.Vars(r)["resourceId"],
+	}, nil
+}
+
+func decodeGetResourceCalendarRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+	if err != nil {
+		return nil, malformed(err)
+	}
+	end, err := time.Parse(time.RFC3339, r.URL.Query().Get("end"))
+	if err != nil {
+		return nil, malformed(err)
+	}
+	return endpoint.GetResourceCalendarRequest{
+		ResourceID: mux.Vars(r)["resourceId"],
+		Start:      start,
+		End:        end,
+	}, nil
+}
+
+func decodeUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoint.UserRequest{
+		UserID: vars["userId"],
+	}, nil
+}
+
+func decodeUpdateUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var req domain.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
+	}
+	return endpoint.UpdateUserRequest{
+		UserID:            vars["userId"],
+		UpdateUserRequest: req,
+	}, nil
+}
+
+func decodeEventRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var req domain.EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
+	}
+	return endpoint.EventRequest{
+		UserID:       vars["userId"],
+		EventID:      vars["eventId"],
+		EventRequest: req,
+	}, nil
+}
+
+func decodeDeleteEventRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoint.EventRequest{
+		UserID:  vars["userId"],
+		EventID: vars["eventId"],
+	}, nil
+}
+
+func decodeGetUserCalendarRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if strings.Contains(r.Header.Get("Accept"), ical.ContentType) {
+		return decodeICalendarRequest(ctx, r)
+	}
+
 	vars := mux.Vars(r)
 	userID := vars["userId"]
 
@@ -53,12 +303,12 @@ func decodeGetUserCalendarRequest(_ context.Context, r *http.Request) (interface
 
 	startTime, err := time.Parse(time.RFC3339, start)
 	if err != nil {
-		return nil, err
+		return nil, malformed(err)
 	}
 
 	endTime, err := time.Parse(time.RFC3339, end)
 	if err != nil {
-		return nil, err
+		return nil, malformed(err)
 	}
 
 	return endpoint.GetUserCalendarRequest{
@@ -68,17 +318,122 @@ func decodeGetUserCalendarRequest(_ context.Context, r *http.Request) (interface
 	}, nil
 }
 
+// decodeICalendarRequest decodes a calendar export. Unlike the JSON calendar
+// the window is optional, since calendar clients subscribe to a fixed URL.
+func decodeICalendarRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := endpoint.GetUserCalendarRequest{
+		UserID:    mux.Vars(r)["userId"],
+		ICalendar: true,
+	}
+
+	var err error
+	if start := r.URL.Query().Get("start"); start != "" {
+		if req.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, malformed(err)
+		}
+	}
+	if end := r.URL.Query().Get("end"); end != "" {
+		if req.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return nil, malformed(err)
+		}
+	}
+	return req, nil
+}
+
+// decodeImportCalendarRequest reads the iCalendar file from the request body
+func decodeImportCalendarRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxImportBytes+1))
+	if err != nil {
+		return nil, malformed(err)
+	}
+	if len(data) > maxImportBytes {
+		return nil, malformed(fmt.Errorf("calendar exceeds %d bytes", maxImportBytes))
+	}
+	return endpoint.ImportCalendarRequest{
+		UserID: mux.Vars(r)["userId"],
+		Data:   data,
+	}, nil
+}
+
+func decodeGetMeetingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoint.GetMeetingRequest{
+		MeetingID: vars["meetingId"],
+	}, nil
+}
+
+func decodeCancelMeetingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoint.CancelMeetingRequest{
+		MeetingID: vars["meetingId"],
+	}, nil
+}
+
+func decodeCancelOccurrenceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	occurrence, err := time.Parse(time.RFC3339, vars["occurrence"])
+	if err != nil {
+		return nil, malformed(err)
+	}
+	return endpoint.CancelOccurrenceRequest{
+		MeetingID:  vars["meetingId"],
+		Occurrence: occurrence,
+	}, nil
+}
+
+func decodeRescheduleMeetingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var req domain.RescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, malformed(err)
+	}
+	if explainRequested(r) {
+		req.Explain = true
+	}
+	return endpoint.RescheduleMeetingRequest{
+		MeetingID:         vars["meetingId"],
+		RescheduleRequest: req,
+	}, nil
+}
+
+// explainRequested reports whether the client asked for a score breakdown with ?explain=true
+func explainRequested(r *http.Request) bool {
+	explain, _ := strconv.ParseBool(r.URL.Query().Get("explain"))
+	return explain
+}
+
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(w).Encode(response)
 }
 
+// encodeCalendarResponse writes iCalendar exports as text/calendar and
+// everything else as JSON
+func encodeCalendarResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	calendar, ok := response.(endpoint.ICalendarResponse)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+	w.Header().Set("Content-Type", ical.ContentType+"; charset=utf-8")
+	_, err := w.Write(ical.Marshal(calendar.Events))
+	return err
+}
+
 func encodeScheduleResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeCreatedResponse(ctx, w, response)
+}
+
+func encodeCreatedResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	return json.NewEncoder(w).Encode(response)
 }
 
+func encodeNoContentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func scheduleHandler(ep kitendpoint.Endpoint, logger log.Logger, options []httptransport.ServerOption) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		server := httptransport.NewServer(
@@ -92,21 +447,65 @@ func scheduleHandler(ep kitendpoint.Endpoint, logger log.Logger, options []httpt
 	}
 }
 
+// errorResponse is the JSON body of every error response. Code is the
+// validation code for invalid fields and a generic code otherwise; Field
+// names the offending request field, if there is one, and MissingIDs the
+// users that were not found.
+type errorResponse struct {
+	Error      string   `json:"error"`
+	Code       string   `json:"code"`
+	Field      string   `json:"field,omitempty"`
+	MissingIDs []string `json:"missingIds,omitempty"`
+}
+
+// malformed marks an error decoding a request, which is reported as 400 Bad Request
+func malformed(err error) error {
+	return fmt.Errorf("%w: %v", service.ErrInvalidRequest, err)
+}
+
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	switch err {
-	case service.ErrInvalidRequest:
-		w.WriteHeader(http.StatusBadRequest)
-	case service.ErrNoAvailableSlot:
-		w.WriteHeader(http.StatusConflict)
-	case service.ErrUserNotFound:
-		w.WriteHeader(http.StatusNotFound)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+	status := http.StatusInternalServerError
+	resp := errorResponse{Error: err.Error(), Code: "internal"}
+	var validation *service.ValidationError
+	var missing *service.MissingUsersError
+	if errors.As(err, &missing) {
+		resp.MissingIDs = missing.IDs
+	}
+	switch {
+	case errors.As(err, &validation):
+		status = http.StatusUnprocessableEntity
+		resp.Code = validation.Code
+		resp.Field = validation.Field
+	case errors.Is(err, service.ErrInvalidRequest):
+		status = http.StatusBadRequest
+		resp.Code = "bad_request"
+	case isAny(err, service.ErrNoAvailableSlot, service.ErrSlotConflict, service.ErrEventConflict, service.ErrMeetingEvent, service.ErrNoMatchingResource):
+		status = http.StatusConflict
+		resp.Code = "conflict"
+	case isAny(err, service.ErrUserNotFound, service.ErrMeetingNotFound, service.ErrEventNotFound, service.ErrOccurrenceNotFound, service.ErrResourceNotFound):
+		status = http.StatusNotFound
+		resp.Code = "not_found"
+	// Storage failures are logged with their cause, which clients don't see
+	case errors.Is(err, service.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+		resp.Code = "unavailable"
+		resp.Error = service.ErrUnavailable.Error()
+	case errors.Is(err, service.ErrInternalError):
+		resp.Error = service.ErrInternalError.Error()
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": err.Error(),
-	})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// isAny reports whether err matches any of the targets
+func isAny(err error, targets ...error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/meeting-scheduler/internal/endpoint"
+	"github.com/meeting-scheduler/internal/service"
+	"github.com/meeting-scheduler/pkg/repository"
+)
+
+func TestErrorResponses(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	handler := NewHTTPHandler(endpoint.MakeEndpoints(service.NewService(repo)), log.NewNopLogger())
+
+	var user struct {
+		ID string `json:"id"`
+	}
+	if resp := serve(t, handler, "POST", "/users", `{"name": "Alice"}`); resp.Code != http.StatusCreated {
+		t.Fatalf("Failed to create a user: %d %s", resp.Code, resp.Body)
+	} else if err := json.Unmarshal(resp.Body.Bytes(), &user); err != nil {
+		t.Fatalf("Failed to decode the user: %v", err)
+	}
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Hour)
+	event := fmt.Sprintf(`{"startTime": %q, "endTime": %q}`, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	if resp := serve(t, handler, "POST", "/users/"+user.ID+"/events", event); resp.Code != http.StatusCreated {
+		t.Fatalf("Failed to create an event: %d %s", resp.Code, resp.Body)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		status   int
+		expected errorResponse
+	}{
+		{
+			name:     "Validation error names the field",
+			method:   "POST",
+			path:     "/users",
+			body:     `{"name": " "}`,
+			status:   http.StatusUnprocessableEntity,
+			expected: errorResponse{Error: "name is required", Code: service.CodeRequired, Field: "name"},
+		},
+		{
+			name:     "Malformed JSON",
+			method:   "POST",
+			path:     "/users",
+			body:     `{"name": `,
+			status:   http.StatusBadRequest,
+			expected: errorResponse{Error: "invalid request parameters: unexpected EOF", Code: "bad_request"},
+		},
+		{
+			name:     "Missing user",
+			method:   "GET",
+			path:     "/users/ghost",
+			status:   http.StatusNotFound,
+			expected: errorResponse{Error: service.ErrUserNotFound.Error(), Code: "not_found"},
+		},
+		{
+			name:     "Overlapping event",
+			method:   "POST",
+			path:     "/users/" + user.ID + "/events",
+			body:     event,
+			status:   http.StatusConflict,
+			expected: errorResponse{Error: service.ErrEventConflict.Error(), Code: "conflict"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(t, handler, tt.method, tt.path, tt.body)
+			checkError(t, resp, tt.status, tt.expected)
+		})
+	}
+}
+
+func TestEncodeStorageErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		expected errorResponse
+	}{
+		{
+			name:     "Unreachable database",
+			err:      fmt.Errorf("%w: get user: dial tcp 10.0.0.5:3306: connection refused", service.ErrUnavailable),
+			status:   http.StatusServiceUnavailable,
+			expected: errorResponse{Error: service.ErrUnavailable.Error(), Code: "unavailable"},
+		},
+		{
+			name:     "Failed query",
+			err:      fmt.Errorf("%w: get user: Error 1054: Unknown column 'time_zone'", service.ErrInternalError),
+			status:   http.StatusInternalServerError,
+			expected: errorResponse{Error: service.ErrInternalError.Error(), Code: "internal"},
+		},
+		{
+			name:     "Missing users",
+			err:      &service.MissingUsersError{IDs: []string{"ghost1", "ghost2"}},
+			status:   http.StatusNotFound,
+			expected: errorResponse{Error: "users not found: ghost1, ghost2", Code: "not_found", MissingIDs: []string{"ghost1", "ghost2"}},
+		},
+		{
+			name:     "Unexpected error",
+			err:      errors.New("boom"),
+			status:   http.StatusInternalServerError,
+			expected: errorResponse{Error: "boom", Code: "internal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			encodeError(context.Background(), tt.err, resp)
+			checkError(t, resp, tt.status, tt.expected)
+		})
+	}
+}
+
+// serve sends a request with the given JSON body to the handler
+func serve(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+// checkError compares the status and JSON body of an error response
+func checkError(t *testing.T, resp *httptest.ResponseRecorder, status int, expected errorResponse) {
+	t.Helper()
+	if resp.Code != status {
+		t.Errorf("Expected status %d but got %d", status, resp.Code)
+	}
+	if contentType := resp.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Expected a JSON response but got %q", contentType)
+	}
+	var body errorResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode the error response %q: %v", resp.Body, err)
+	}
+	if fmt.Sprint(body) != fmt.Sprint(expected) {
+		t.Errorf("Expected body %+v but got %+v", expected, body)
+	}
+}
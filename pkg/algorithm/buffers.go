@@ -0,0 +1,47 @@
+package algorithm
+
+import (
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// blockedEvents widens the participants' events to all the time they rule out
+// for the new meeting: the travel time of each event on both sides, and the
+// buffers the participant needs between the meeting and their other events,
+// the longer of the new meeting's and those the event was booked with.
+// A slot that overlaps none of the widened events leaves every participant
+// their buffers, so they are hard constraints rather than a scoring criterion.
+func blockedEvents(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent, o *options) map[string][]domain.CalendarEvent {
+	blocked := make(map[string][]domain.CalendarEvent, len(events))
+	for id, userEvents := range events {
+		p := o.participants[id]
+		before := maxDuration(minutes(req.BufferBeforeMinutes), p.bufferBefore)
+		after := maxDuration(minutes(req.BufferAfterMinutes), p.bufferAfter)
+
+		widened := make([]domain.CalendarEvent, len(userEvents))
+		for i, event := range userEvents {
+			travel := minutes(event.TravelMinutes)
+			// The meeting has to end its after-buffer before the event
+			// starts, and start its before-buffer after the event ends
+			event.StartTime = event.StartTime.Add(-travel - maxDuration(after, minutes(event.BufferBeforeMinutes)))
+			event.EndTime = event.EndTime.Add(travel + maxDuration(before, minutes(event.BufferAfterMinutes)))
+			// The travel time and buffers are part of the widened event now
+			event.TravelMinutes, event.BufferBeforeMinutes, event.BufferAfterMinutes = 0, 0, 0
+			widened[i] = event
+		}
+		blocked[id] = widened
+	}
+	return blocked
+}
+
+// blockedInterval returns the time an event occupies including its travel
+// time and buffers
+func blockedInterval(event domain.CalendarEvent) (time.Time, time.Time) {
+	travel := minutes(event.TravelMinutes)
+	return event.StartTime.Add(-travel - minutes(event.BufferBeforeMinutes)), event.EndTime.Add(travel + minutes(event.BufferAfterMinutes))
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
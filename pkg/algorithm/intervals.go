@@ -0,0 +1,155 @@
+package algorithm
+
+import (
+	"sort"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// Interval is a half-open time interval [Start, End)
+type Interval = domain.Interval
+
+// MergeBusy collects the events of all participants into sorted,
+// non-overlapping busy intervals. Touching intervals are merged as well. An
+// event's travel time and buffers count as busy, as they do in
+// IsSlotAvailable.
+func MergeBusy(events map[string][]domain.CalendarEvent) []Interval {
+	var intervals []Interval
+	for _, userEvents := range events {
+		for _, event := range userEvents {
+			start, end := blockedInterval(event)
+			intervals = append(intervals, Interval{Start: start, End: end})
+		}
+	}
+	return mergeIntervals(intervals)
+}
+
+// mergeIntervals sorts the intervals by start time and sweeps over them once,
+// joining every interval that overlaps or touches the one before it. Empty
+// intervals are dropped.
+func mergeIntervals(intervals []Interval) []Interval {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.Start.Before(interval.End) {
+			sorted = append(sorted, interval)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	merged := make([]Interval, 0, len(sorted))
+	for _, interval := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// FreeIntervals returns the gaps within [start, end) that are not covered by
+// busy, which must be sorted and non-overlapping as returned by MergeBusy
+func FreeIntervals(busy []Interval, start, end time.Time) []Interval {
+	var free []Interval
+	cursor := start
+	for _, interval := range busy {
+		if !interval.End.After(cursor) {
+			continue
+		}
+		if !interval.Start.Before(end) {
+			break
+		}
+		if interval.Start.After(cursor) {
+			free = append(free, Interval{Start: cursor, End: interval.Start})
+		}
+		cursor = interval.End
+	}
+	if cursor.Before(end) {
+		free = append(free, Interval{Start: cursor, End: end})
+	}
+	return free
+}
+
+// FreeBusy returns each participant's merged busy intervals within
+// [start, end), clipped to the window, and the intervals in which all of them
+// are free
+func FreeBusy(events map[string][]domain.CalendarEvent, start, end time.Time) (map[string][]Interval, []Interval) {
+	busy := make(map[string][]Interval, len(events))
+	for userID, userEvents := range events {
+		busy[userID] = clipIntervals(MergeBusy(map[string][]domain.CalendarEvent{userID: userEvents}), start, end)
+	}
+	return busy, FreeIntervals(MergeBusy(events), start, end)
+}
+
+// clipIntervals cuts sorted intervals down to [start, end), dropping those outside it
+func clipIntervals(intervals []Interval, start, end time.Time) []Interval {
+	clipped := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.Start.Before(start) {
+			interval.Start = start
+		}
+		if interval.End.After(end) {
+			interval.End = end
+		}
+		if interval.Start.Before(interval.End) {
+			clipped = append(clipped, interval)
+		}
+	}
+	return clipped
+}
+
+// eventIndex keeps a participant's event boundaries sorted so the scoring
+// functions can find the events near a slot with binary searches instead of
+// scanning the whole calendar for every candidate
+type eventIndex struct {
+	userID string
+	starts []time.Time
+	ends   []time.Time
+}
+
+func newEventIndex(userID string, events []domain.CalendarEvent) eventIndex {
+	idx := eventIndex{
+		userID: userID,
+		starts: make([]time.Time, 0, len(events)),
+		ends:   make([]time.Time, 0, len(events)),
+	}
+	for _, event := range events {
+		idx.starts = append(idx.starts, event.StartTime)
+		idx.ends = append(idx.ends, event.EndTime)
+	}
+	sort.Slice(idx.starts, func(i, j int) bool { return idx.starts[i].Before(idx.starts[j]) })
+	sort.Slice(idx.ends, func(i, j int) bool { return idx.ends[i].Before(idx.ends[j]) })
+	return idx
+}
+
+// newEventIndexes builds one index per participant, ordered by participant ID
+// so that scores are always accumulated in the same order
+func newEventIndexes(events map[string][]domain.CalendarEvent) []eventIndex {
+	ids := make([]string, 0, len(events))
+	for id := range events {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	indexes := make([]eventIndex, 0, len(ids))
+	for _, id := range ids {
+		indexes = append(indexes, newEventIndex(id, events[id]))
+	}
+	return indexes
+}
+
+// countBefore returns the number of times in sorted that are before t
+func countBefore(sorted []time.Time, t time.Time) int {
+	return sort.Search(len(sorted), func(i int) bool { return !sorted[i].Before(t) })
+}
+
+// countNotAfter returns the number of times in sorted that are at or before t
+func countNotAfter(sorted []time.Time, t time.Time) int {
+	return sort.Search(len(sorted), func(i int) bool { return sorted[i].After(t) })
+}
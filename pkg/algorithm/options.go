@@ -0,0 +1,103 @@
+package algorithm
+
+import (
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// Option customizes how FindOptimalSlot and RankSlots score candidate slots
+type Option func(*options)
+
+type options struct {
+	participants map[string]participant
+	scorer       Scorer
+	series       *recurringSeries
+	// resources are the pools of resources to book with each slot
+	resources [][]string
+}
+
+// participant holds the scheduling preferences of a single participant
+type participant struct {
+	id string
+	// location is the participant's time zone; nil means a slot is judged in
+	// whatever location its start time carries
+	location     *time.Location
+	workdayStart int
+	workdayEnd   int
+	// bufferBefore and bufferAfter are the free time the participant needs
+	// around a meeting
+	bufferBefore time.Duration
+	bufferAfter  time.Duration
+}
+
+// defaultParticipant is used for participants without a known profile
+var defaultParticipant = participant{
+	workdayStart: workDayStart,
+	workdayEnd:   workDayEnd,
+}
+
+// WithParticipants scores slots in each participant's own time zone and
+// working hours instead of the default 9 AM - 5 PM in the slot's location, and
+// keeps each participant's buffers free around the slot
+func WithParticipants(users ...domain.User) Option {
+	return func(o *options) {
+		for _, user := range users {
+			start, end := user.WorkingHours()
+			o.participants[user.ID] = participant{
+				id:           user.ID,
+				location:     user.Location(),
+				workdayStart: start,
+				workdayEnd:   end,
+				bufferBefore: minutes(user.BufferBeforeMinutes),
+				bufferAfter:  minutes(user.BufferAfterMinutes),
+			}
+		}
+	}
+}
+
+// WithScorer scores slots with the given strategy instead of DefaultScorer
+func WithScorer(scorer Scorer) Option {
+	return func(o *options) {
+		if scorer != nil {
+			o.scorer = scorer
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		participants: make(map[string]participant),
+		scorer:       DefaultScorer(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// participantsFor returns the profiles of the requested participants, falling
+// back to the default profile for anyone the caller did not describe
+func (o *options) participantsFor(ids []string) []participant {
+	if len(ids) == 0 {
+		return []participant{defaultParticipant}
+	}
+	participants := make([]participant, 0, len(ids))
+	for _, id := range ids {
+		p, ok := o.participants[id]
+		if !ok {
+			p = defaultParticipant
+			p.id = id
+		}
+		participants = append(participants, p)
+	}
+	return participants
+}
+
+// localHour returns the hour of t in the participant's time zone
+func (p participant) localHour(t time.Time) int {
+	if p.location != nil {
+		t = t.In(p.location)
+	}
+	return t.Hour()
+}
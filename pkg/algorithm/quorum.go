@@ -0,0 +1,72 @@
+package algorithm
+
+import (
+	"sort"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// splitOptional separates the calendars of the request's optional
+// participants from everyone else's. Only the remaining calendars have to be
+// free for a slot to be available.
+func splitOptional(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent) (required, optional map[string][]domain.CalendarEvent) {
+	if len(req.OptionalParticipantIDs) == 0 {
+		return events, nil
+	}
+
+	isOptional := make(map[string]bool, len(req.OptionalParticipantIDs))
+	for _, id := range req.OptionalParticipantIDs {
+		isOptional[id] = true
+	}
+
+	required = make(map[string][]domain.CalendarEvent, len(events))
+	optional = make(map[string][]domain.CalendarEvent, len(req.OptionalParticipantIDs))
+	for id, userEvents := range events {
+		if isOptional[id] {
+			optional[id] = userEvents
+		} else {
+			required[id] = userEvents
+		}
+	}
+	return required, optional
+}
+
+// applyQuorum records which optional participants are busy during each slot
+// and drops the slots that fewer than req.MinAttendees participants can attend
+func applyQuorum(slots []TimeSlot, req domain.ScheduleRequest, optional map[string][]domain.CalendarEvent) []TimeSlot {
+	if len(req.OptionalParticipantIDs) == 0 {
+		return slots
+	}
+
+	busy := make([][]Interval, len(req.OptionalParticipantIDs))
+	for i, id := range req.OptionalParticipantIDs {
+		busy[i] = MergeBusy(map[string][]domain.CalendarEvent{id: optional[id]})
+	}
+
+	kept := slots[:0]
+	for _, slot := range slots {
+		slot.Unavailable = nil
+		for i, id := range req.OptionalParticipantIDs {
+			if isBusy(busy[i], slot.Start, slot.End) {
+				slot.Unavailable = append(slot.Unavailable, id)
+			}
+		}
+		if attendees(req, slot) >= req.MinAttendees {
+			kept = append(kept, slot)
+		}
+	}
+	return kept
+}
+
+// attendees returns the number of participants who can attend the slot
+func attendees(req domain.ScheduleRequest, slot TimeSlot) int {
+	return len(req.ParticipantIDs) + len(req.OptionalParticipantIDs) - len(slot.Unavailable)
+}
+
+// isBusy reports whether [start, end) overlaps any of the sorted,
+// non-overlapping busy intervals
+func isBusy(busy []Interval, start, end time.Time) bool {
+	i := sort.Search(len(busy), func(i int) bool { return busy[i].End.After(start) })
+	return i < len(busy) && busy[i].Start.Before(end)
+}
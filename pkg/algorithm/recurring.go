@@ -0,0 +1,68 @@
+package algorithm
+
+import (
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+	"github.com/meeting-scheduler/pkg/recurrence"
+)
+
+// recurringSeries describes the series a slot would start
+type recurringSeries struct {
+	rule *recurrence.Rule
+	// threshold is the fraction of occurrences that must be free
+	threshold float64
+	// loc is the time zone the series repeats in
+	loc *time.Location
+}
+
+// WithRecurrence treats every candidate slot as the first occurrence of a
+// series following rule and repeating in loc (UTC if nil). A slot is only
+// available if at least the threshold fraction (0-1] of its occurrences within
+// recurrence.Horizon are free for the required participants; a threshold of
+// zero requires all of them. The events passed to RankSlots must already be
+// expanded over that horizon.
+func WithRecurrence(rule *recurrence.Rule, threshold float64, loc *time.Location) Option {
+	return func(o *options) {
+		if threshold <= 0 || threshold > 1 {
+			threshold = 1
+		}
+		if loc == nil {
+			loc = time.UTC
+		}
+		o.series = &recurringSeries{rule: rule, threshold: threshold, loc: loc}
+	}
+}
+
+// applyRecurrence drops the slots whose series would have too many busy
+// occurrences and records the busy ones of the remaining slots in Skipped
+func applyRecurrence(slots []TimeSlot, required map[string][]domain.CalendarEvent, series *recurringSeries) []TimeSlot {
+	if series == nil {
+		return slots
+	}
+
+	busy := MergeBusy(required)
+	kept := slots[:0]
+	for _, slot := range slots {
+		duration := slot.End.Sub(slot.Start)
+		occurrences := series.occurrences(slot)
+
+		var skipped []time.Time
+		for _, start := range occurrences {
+			if isBusy(busy, start, start.Add(duration)) {
+				skipped = append(skipped, start)
+			}
+		}
+		free := len(occurrences) - len(skipped)
+		if float64(free) >= series.threshold*float64(len(occurrences)) {
+			slot.Skipped = skipped
+			kept = append(kept, slot)
+		}
+	}
+	return kept
+}
+
+// occurrences returns the start times of the series starting at slot within recurrence.Horizon
+func (s *recurringSeries) occurrences(slot TimeSlot) []time.Time {
+	return s.rule.Occurrences(slot.Start.In(s.loc), slot.Start.Add(recurrence.Horizon))
+}
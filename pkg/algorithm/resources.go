@@ -0,0 +1,134 @@
+package algorithm
+
+import (
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// WithResources books a resource from each pool together with every slot.
+// A pool lists the IDs of interchangeable resources in order of preference,
+// and a slot is only available if each pool has a resource that is free
+// during it (and during all of its occurrences that take place, for recurring
+// meetings). No resource is picked for two pools. The events passed to
+// RankSlots must include the resources' calendars, keyed by resource ID;
+// those calendars do not affect the slot's score.
+func WithResources(pools ...[]string) Option {
+	return func(o *options) {
+		o.resources = append(o.resources, pools...)
+	}
+}
+
+// splitResources separates the calendars of the resources in the pools from
+// the participants' calendars
+func splitResources(events map[string][]domain.CalendarEvent, pools [][]string) (participants, resources map[string][]domain.CalendarEvent) {
+	if len(pools) == 0 {
+		return events, nil
+	}
+
+	resources = make(map[string][]domain.CalendarEvent)
+	for _, pool := range pools {
+		for _, id := range pool {
+			resources[id] = events[id]
+		}
+	}
+	participants = make(map[string][]domain.CalendarEvent, len(events))
+	for id, userEvents := range events {
+		if _, ok := resources[id]; !ok {
+			participants[id] = userEvents
+		}
+	}
+	return participants, resources
+}
+
+// applyResources picks a free resource from every pool for each slot,
+// recording them in Resources, and drops the slots for which that is not
+// possible
+func applyResources(slots []TimeSlot, events map[string][]domain.CalendarEvent, pools [][]string, series *recurringSeries) []TimeSlot {
+	if len(pools) == 0 {
+		return slots
+	}
+
+	busy := make(map[string][]Interval, len(events))
+	for id, resourceEvents := range events {
+		busy[id] = MergeBusy(map[string][]domain.CalendarEvent{id: resourceEvents})
+	}
+
+	kept := slots[:0]
+	for _, slot := range slots {
+		duration := slot.End.Sub(slot.Start)
+		occurrences := takingPlace(slot, series)
+		free := make(map[string]bool)
+		isFree := func(id string) bool {
+			if known, ok := free[id]; ok {
+				return known
+			}
+			free[id] = true
+			for _, start := range occurrences {
+				if isBusy(busy[id], start, start.Add(duration)) {
+					free[id] = false
+					break
+				}
+			}
+			return free[id]
+		}
+
+		if resources, ok := assignResources(pools, isFree); ok {
+			slot.Resources = resources
+			kept = append(kept, slot)
+		}
+	}
+	return kept
+}
+
+// assignResources picks a distinct free resource from every pool, preferring
+// earlier resources in each pool. It backtracks when a resource picked for an
+// earlier pool is the only free one left for a later pool.
+func assignResources(pools [][]string, isFree func(string) bool) ([]string, bool) {
+	assigned := make([]string, len(pools))
+	used := make(map[string]bool, len(pools))
+
+	var assign func(i int) bool
+	assign = func(i int) bool {
+		if i == len(pools) {
+			return true
+		}
+		for _, id := range pools[i] {
+			if used[id] || !isFree(id) {
+				continue
+			}
+			used[id] = true
+			assigned[i] = id
+			if assign(i + 1) {
+				return true
+			}
+			used[id] = false
+		}
+		return false
+	}
+
+	if !assign(0) {
+		return nil, false
+	}
+	return assigned, true
+}
+
+// takingPlace returns the start times of the slot's occurrences that are not
+// skipped; a slot that does not start a series has a single occurrence
+func takingPlace(slot TimeSlot, series *recurringSeries) []time.Time {
+	if series == nil {
+		return []time.Time{slot.Start}
+	}
+
+	skipped := make(map[int64]bool, len(slot.Skipped))
+	for _, start := range slot.Skipped {
+		skipped[start.UnixNano()] = true
+	}
+	var occurrences []time.Time
+	for _, start := range series.occurrences(slot) {
+		if !skipped[start.UnixNano()] {
+			occurrences = append(occurrences, start)
+		}
+	}
+	return occurrences
+}
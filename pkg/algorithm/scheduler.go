@@ -1,6 +1,7 @@
 package algorithm
 
 import (
+	"math"
 	"sort"
 	"time"
 
@@ -8,72 +9,164 @@ import (
 )
 
 const (
-	// Time slot scoring weights
-	workingHoursWeight    = 1.0
-	earlySlotWeight       = 0.8
-	gapMinimizationWeight = 0.6
-	bufferTimeWeight      = 0.4
-
 	// Buffer time in minutes
 	desiredBufferTime = 15
 
-	// Working hours
-	workDayStart = 9  // 9 AM
-	workDayEnd   = 17 // 5 PM
+	// Default working hours, used for participants without their own
+	workDayStart = domain.DefaultWorkdayStart
+	workDayEnd   = domain.DefaultWorkdayEnd
+)
+
+// Names of the scoring criteria as reported in score breakdowns
+const (
+	CriterionWorkingHours    = "workingHours"
+	CriterionEarlySlot       = "earlySlot"
+	CriterionAfternoon       = "afternoon"
+	CriterionGapMinimization = "gapMinimization"
+	CriterionBufferTime      = "bufferTime"
 )
 
 type TimeSlot struct {
-	Start time.Time
-	End   time.Time
-	Score float64
+	Start     time.Time
+	End       time.Time
+	Score     float64
+	Breakdown []domain.CriterionScore
+	// Unavailable lists the optional participants who are busy during the slot
+	Unavailable []string
+	// Skipped lists the occurrences of a recurring slot that are not free
+	Skipped []time.Time
+	// Resources holds the resource picked from each pool (see WithResources)
+	Resources []string
 }
 
 // FindOptimalSlot finds the best time slot for a meeting based on various criteria
-func FindOptimalSlot(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent) (*TimeSlot, error) {
+func FindOptimalSlot(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent, opts ...Option) (*TimeSlot, error) {
+	slots, err := RankSlots(req, events, 1, opts...)
+	if err != nil || len(slots) == 0 {
+		return nil, err
+	}
+	return &slots[0], nil
+}
+
+// RankSlots returns up to limit available slots ordered from best to worst.
+// Every required participant must be free during a slot, including their
+// buffers around it and the travel time around their events, and at least
+// req.MinAttendees participants in total. For recurring meetings (see
+// WithRecurrence) enough of the slot's later occurrences must be free too, and
+// with WithResources a free resource must be found for each pool. Slots more
+// optional participants can attend rank first, then slots with higher scores;
+// slots that tie keep their chronological order. A limit of zero or less
+// returns every available slot.
+func RankSlots(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent, limit int, opts ...Option) ([]TimeSlot, error) {
+	o := newOptions(opts)
+	events, resources := splitResources(events, o.resources)
+	required, optional := splitOptional(req, blockedEvents(req, events, o))
+
 	// Get all available slots
-	availableSlots := findAvailableSlots(req, events)
+	availableSlots := findAvailableSlots(req, required)
+	availableSlots = applyRecurrence(availableSlots, required, o.series)
+	availableSlots = applyQuorum(availableSlots, req, optional)
+	availableSlots = applyResources(availableSlots, resources, o.resources, o.series)
 	if len(availableSlots) == 0 {
 		return nil, nil
 	}
 
 	// Score each slot
-	scoredSlots := scoreSlots(availableSlots, events)
+	participantIDs := append(append([]string(nil), req.ParticipantIDs...), req.OptionalParticipantIDs...)
+	scoredSlots := scoreSlots(availableSlots, events, o.participantsFor(participantIDs), o.scorer)
 
-	// Sort by score (highest first)
-	sort.Slice(scoredSlots, func(i, j int) bool {
+	// Sort by attendance, then by score (highest first)
+	sort.SliceStable(scoredSlots, func(i, j int) bool {
+		if a, b := len(scoredSlots[i].Unavailable), len(scoredSlots[j].Unavailable); a != b {
+			return a < b
+		}
 		return scoredSlots[i].Score > scoredSlots[j].Score
 	})
 
-	return &scoredSlots[0], nil
+	if limit > 0 && len(scoredSlots) > limit {
+		scoredSlots = scoredSlots[:limit]
+	}
+	return scoredSlots, nil
 }
 
-// findAvailableSlots finds all possible time slots that work for all participants
+// findAvailableSlots finds all possible time slots that work for all
+// participants. The participants' calendars are merged into busy intervals
+// once, and candidates are only generated inside the free gaps between them,
+// on the grid described by the request's granularity and alignment.
 func findAvailableSlots(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent) []TimeSlot {
 	var slots []TimeSlot
-	current := req.TimeRange.Start
-
-	for current.Before(req.TimeRange.End) {
-		slotEnd := current.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	anchor, step := slotGrid(req)
+	free := FreeIntervals(MergeBusy(events), req.TimeRange.Start, req.TimeRange.End)
+
+	for _, gap := range free {
+		current := anchor
+		if gap.Start.After(anchor) {
+			steps := (gap.Start.Sub(anchor) + step - 1) / step
+			current = anchor.Add(steps * step)
+		}
 
-		if slotEnd.Before(req.TimeRange.End) && isSlotAvailable(current, slotEnd, events) {
+		for !current.Add(duration).After(gap.End) {
 			slots = append(slots, TimeSlot{
 				Start: current,
-				End:   slotEnd,
+				End:   current.Add(duration),
 			})
+			current = current.Add(step)
 		}
-
-		current = current.Add(15 * time.Minute)
 	}
 
 	return slots
 }
 
-// isSlotAvailable checks if a time slot is available for all participants
-func isSlotAvailable(start, end time.Time, events map[string][]domain.CalendarEvent) bool {
+// slotGrid returns the first candidate start time and the distance between
+// candidates. Aligned grids use the larger of the granularity and the
+// alignment period, so that every candidate falls on an aligned clock time.
+func slotGrid(req domain.ScheduleRequest) (time.Time, time.Duration) {
+	granularity := req.GranularityMinutes
+	if granularity <= 0 {
+		granularity = domain.DefaultGranularityMinutes
+	}
+	step := time.Duration(granularity) * time.Minute
+
+	switch req.Alignment {
+	case domain.AlignGranularity:
+	case domain.AlignHalfHour:
+		step = maxDuration(step, 30*time.Minute)
+	case domain.AlignHour:
+		step = maxDuration(step, time.Hour)
+	default:
+		return req.TimeRange.Start, step
+	}
+
+	return alignToClock(req.TimeRange.Start, step), step
+}
+
+// alignToClock rounds t up to the next clock time that is a whole multiple of
+// period after midnight in t's location
+func alignToClock(t time.Time, period time.Duration) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if remainder := t.Sub(midnight) % period; remainder != 0 {
+		return t.Add(period - remainder)
+	}
+	return t
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// IsSlotAvailable checks if a time slot is available for all participants.
+// Slots and events are half-open intervals [start, end), so a slot may begin
+// exactly when an event ends and vice versa. An event's travel time and
+// buffers count as part of the event.
+func IsSlotAvailable(start, end time.Time, events map[string][]domain.CalendarEvent) bool {
 	for _, userEvents := range events {
 		for _, event := range userEvents {
-			// Check for overlap
-			if !(end.Before(event.StartTime) || start.After(event.EndTime)) {
+			eventStart, eventEnd := blockedInterval(event)
+			if overlaps(start, end, eventStart, eventEnd) {
 				return false
 			}
 		}
@@ -81,120 +174,139 @@ func isSlotAvailable(start, end time.Time, events map[string][]domain.CalendarEv
 	return true
 }
 
-// scoreSlots scores each available slot based on our criteria
-func scoreSlots(slots []TimeSlot, events map[string][]domain.CalendarEvent) []TimeSlot {
+// overlaps reports whether the half-open intervals [aStart, aEnd) and [bStart, bEnd) intersect
+func overlaps(aStart, aEnd, bStart, bEnd time.Time) bool {
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
+}
+
+// scoreSlots scores each available slot with the given scorer
+func scoreSlots(slots []TimeSlot, events map[string][]domain.CalendarEvent, profiles []participant, scorer Scorer) []TimeSlot {
+	p := &Participants{
+		profiles: profiles,
+		indexes:  newEventIndexes(events),
+	}
 	for i := range slots {
-		slots[i].Score = calculateSlotScore(slots[i], events)
+		slots[i].Breakdown = scorer.Score(slots[i], p)
+		slots[i].Score = 0
+		for _, criterion := range slots[i].Breakdown {
+			slots[i].Score += criterion.Weighted
+		}
 	}
 	return slots
 }
 
-// calculateSlotScore calculates a score for a time slot based on various criteria
-func calculateSlotScore(slot TimeSlot, events map[string][]domain.CalendarEvent) float64 {
-	var score float64
-
-	score += workingHoursScore(slot) * workingHoursWeight
-
-	score += earlySlotScore(slot) * earlySlotWeight
-
-	score += gapMinimizationScore(slot, events) * gapMinimizationWeight
-
-	score += bufferTimeScore(slot, events) * bufferTimeWeight
-
-	return score
+func newCriterionScore(name string, score, weight float64, participants []domain.ParticipantScore) domain.CriterionScore {
+	return domain.CriterionScore{
+		Name:         name,
+		Score:        score,
+		Weight:       weight,
+		Weighted:     score * weight,
+		Participants: participants,
+	}
 }
 
-// workingHoursScore prefers slots during working hours
-func workingHoursScore(slot TimeSlot) float64 {
-	hour := slot.Start.Hour()
+// workingHoursScore prefers slots during the participant's working hours
+func workingHoursScore(slot TimeSlot, p participant) float64 {
+	hour := p.localHour(slot.Start)
 
-	if hour >= workDayStart && hour < workDayEnd {
+	if hour >= p.workdayStart && hour < p.workdayEnd {
 		return 1.0
 	}
 
-	if hour >= workDayStart-1 && hour < workDayStart || hour >= workDayEnd && hour < workDayEnd+1 {
+	if hour >= p.workdayStart-1 && hour < p.workdayStart || hour >= p.workdayEnd && hour < p.workdayEnd+1 {
 		return 0.5
 	}
 
 	return 0.0
 }
 
-func earlySlotScore(slot TimeSlot) float64 {
-	hour := float64(slot.Start.Hour())
+// earlySlotScore prefers slots early in the participant's working day
+func earlySlotScore(slot TimeSlot, p participant) float64 {
+	hour := float64(p.localHour(slot.Start))
+	start, end := float64(p.workdayStart), float64(p.workdayEnd)
 
-	if hour >= float64(workDayStart) && hour <= float64(workDayEnd) {
-		return 1.0 - (hour-float64(workDayStart))/float64(workDayEnd-workDayStart)
+	if hour >= start && hour <= end {
+		return 1.0 - (hour-start)/(end-start)
 	}
 
 	return 0.0
 }
 
-func gapMinimizationScore(slot TimeSlot, events map[string][]domain.CalendarEvent) float64 {
-	var totalScore float64
-	count := 0
+// afternoonScore prefers slots in the afternoon of the participant's working
+// day, rising through the morning towards noon
+func afternoonScore(slot TimeSlot, p participant) float64 {
+	hour := float64(p.localHour(slot.Start))
+	start, end := float64(p.workdayStart), float64(p.workdayEnd)
+	noon := math.Max(12, start)
+
+	switch {
+	case hour < start || hour >= end:
+		return 0.0
+	case hour >= noon:
+		return 1.0
+	default:
+		return (hour - start + 1) / (noon - start + 1)
+	}
+}
 
-	for _, userEvents := range events {
-		score := 1.0 // Default score for perfect back-to-back scheduling
+// gapMinimizationScore averages gapMinimizationScoreFor over all participants
+func gapMinimizationScore(slot TimeSlot, indexes []eventIndex) float64 {
+	return averageOverCalendars(slot, indexes, gapMinimizationScoreFor)
+}
 
-		for _, event := range userEvents {
-			if event.EndTime.Before(slot.Start) {
-				gap := slot.Start.Sub(event.EndTime).Minutes()
-				if gap < desiredBufferTime {
-					score *= 0.5 // Penalize small gaps
-				} else if gap > 60 {
-					score *= 0.8 // Slightly penalize large gaps
-				}
-			}
+// gapMinimizationScoreFor penalizes every event that leaves a gap shorter
+// than the desired buffer (0.5) or longer than an hour (0.8) next to the slot.
+// Events that exactly touch the slot leave no gap at all and are not penalized.
+func gapMinimizationScoreFor(slot TimeSlot, idx eventIndex) float64 {
+	buffer := desiredBufferTime * time.Minute
 
-			if event.StartTime.After(slot.End) {
-				gap := event.StartTime.Sub(slot.End).Minutes()
-				if gap < desiredBufferTime {
-					score *= 0.5
-				} else if gap > 60 {
-					score *= 0.8
-				}
-			}
-		}
+	// Events ending before the slot start
+	smallGaps := countBefore(idx.ends, slot.Start) - countNotAfter(idx.ends, slot.Start.Add(-buffer))
+	largeGaps := countBefore(idx.ends, slot.Start.Add(-time.Hour))
 
-		totalScore += score
-		count++
-	}
+	// Events starting after the slot end
+	smallGaps += countBefore(idx.starts, slot.End.Add(buffer)) - countNotAfter(idx.starts, slot.End)
+	largeGaps += len(idx.starts) - countNotAfter(idx.starts, slot.End.Add(time.Hour))
 
-	if count == 0 {
-		return 1.0
-	}
-	return totalScore / float64(count)
+	// Default score of 1.0 for perfect back-to-back scheduling
+	return math.Pow(0.5, float64(smallGaps)) * math.Pow(0.8, float64(largeGaps))
 }
 
-func bufferTimeScore(slot TimeSlot, events map[string][]domain.CalendarEvent) float64 {
-	var totalScore float64
-	count := 0
-
-	for _, userEvents := range events {
-		score := 1.0
+// bufferTimeScore averages bufferTimeScoreFor over all participants
+func bufferTimeScore(slot TimeSlot, indexes []eventIndex) float64 {
+	return averageOverCalendars(slot, indexes, bufferTimeScoreFor)
+}
 
-		for _, event := range userEvents {
-			if event.EndTime.Before(slot.Start) {
-				bufferBefore := slot.Start.Sub(event.EndTime).Minutes()
-				if bufferBefore < desiredBufferTime {
-					score *= float64(bufferBefore) / float64(desiredBufferTime)
-				}
-			}
+// bufferTimeScoreFor scales the score down for every event closer to the
+// slot than the desired buffer, in proportion to how much of the buffer is left
+func bufferTimeScoreFor(slot TimeSlot, idx eventIndex) float64 {
+	buffer := desiredBufferTime * time.Minute
+	score := 1.0
 
-			if event.StartTime.After(slot.End) {
-				bufferAfter := event.StartTime.Sub(slot.End).Minutes()
-				if bufferAfter < desiredBufferTime {
-					score *= float64(bufferAfter) / float64(desiredBufferTime)
-				}
-			}
-		}
+	from, to := countNotAfter(idx.ends, slot.Start.Add(-buffer)), countNotAfter(idx.ends, slot.Start)
+	for _, end := range idx.ends[from:to] {
+		bufferBefore := slot.Start.Sub(end).Minutes()
+		score *= bufferBefore / float64(desiredBufferTime)
+	}
 
-		totalScore += score
-		count++
+	from, to = countBefore(idx.starts, slot.End), countBefore(idx.starts, slot.End.Add(buffer))
+	for _, start := range idx.starts[from:to] {
+		bufferAfter := start.Sub(slot.End).Minutes()
+		score *= bufferAfter / float64(desiredBufferTime)
 	}
 
-	if count == 0 {
+	return score
+}
+
+// averageOverCalendars averages a calendar criterion over the participants'
+// calendars; without any calendars every slot scores 1.0
+func averageOverCalendars(slot TimeSlot, indexes []eventIndex, score func(TimeSlot, eventIndex) float64) float64 {
+	if len(indexes) == 0 {
 		return 1.0
 	}
-	return totalScore / float64(count)
+	var total float64
+	for _, idx := range indexes {
+		total += score(slot, idx)
+	}
+	return total / float64(len(indexes))
 }
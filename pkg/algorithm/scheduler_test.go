@@ -1,10 +1,14 @@
 package algorithm
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
 
 	"github.com/meeting-scheduler/internal/domain"
+	"github.com/meeting-scheduler/pkg/recurrence"
 )
 
 func TestFindOptimalSlot(t *testing.T) {
@@ -128,6 +132,89 @@ func TestFindOptimalSlot(t *testing.T) {
 			expectedStart: "2024-09-01T09:00:00Z",
 			expectedEnd:   "2024-09-01T10:00:00Z",
 		},
+		{
+			name: "Back-to-back after an event, ending at the window end",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: parseTime("2024-09-01T09:00:00Z"),
+					End:   parseTime("2024-09-01T11:00:00Z"),
+				},
+			},
+			events: map[string][]domain.CalendarEvent{
+				"user1": {
+					{
+						StartTime: parseTime("2024-09-01T09:00:00Z"),
+						EndTime:   parseTime("2024-09-01T10:00:00Z"),
+					},
+				},
+			},
+			expectSlot:    true,
+			expectedStart: "2024-09-01T10:00:00Z",
+			expectedEnd:   "2024-09-01T11:00:00Z",
+		},
+		{
+			name: "Back-to-back before an event",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: parseTime("2024-09-01T09:00:00Z"),
+					End:   parseTime("2024-09-01T11:00:00Z"),
+				},
+			},
+			events: map[string][]domain.CalendarEvent{
+				"user1": {
+					{
+						StartTime: parseTime("2024-09-01T10:00:00Z"),
+						EndTime:   parseTime("2024-09-01T11:00:00Z"),
+					},
+				},
+			},
+			expectSlot:    true,
+			expectedStart: "2024-09-01T09:00:00Z",
+			expectedEnd:   "2024-09-01T10:00:00Z",
+		},
+		{
+			name: "Window exactly as long as the meeting",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: parseTime("2024-09-01T09:00:00Z"),
+					End:   parseTime("2024-09-01T10:00:00Z"),
+				},
+			},
+			events:        map[string][]domain.CalendarEvent{},
+			expectSlot:    true,
+			expectedStart: "2024-09-01T09:00:00Z",
+			expectedEnd:   "2024-09-01T10:00:00Z",
+		},
+		{
+			name: "Gap shorter than the meeting",
+			request: domain.ScheduleRequest{
+				ParticipantIDs:  []string{"user1"},
+				DurationMinutes: 60,
+				TimeRange: domain.TimeRange{
+					Start: parseTime("2024-09-01T09:00:00Z"),
+					End:   parseTime("2024-09-01T11:00:00Z"),
+				},
+			},
+			events: map[string][]domain.CalendarEvent{
+				"user1": {
+					{
+						StartTime: parseTime("2024-09-01T09:00:00Z"),
+						EndTime:   parseTime("2024-09-01T09:30:00Z"),
+					},
+					{
+						StartTime: parseTime("2024-09-01T10:15:00Z"),
+						EndTime:   parseTime("2024-09-01T11:00:00Z"),
+					},
+				},
+			},
+			expectSlot: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,10 +292,1036 @@ func TestWorkingHoursScore(t *testing.T) {
 				End:   startTime.Add(time.Hour),
 			}
 
-			score := workingHoursScore(slot)
+			score := workingHoursScore(slot, defaultParticipant)
 			if score != tt.expected {
 				t.Errorf("Expected score %v, got %v", tt.expected, score)
 			}
 		})
 	}
 }
+
+func TestRankSlots(t *testing.T) {
+	parseTime := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: parseTime("2024-09-01T07:00:00Z"),
+			End:   parseTime("2024-09-01T19:00:00Z"),
+		},
+	}
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			{
+				StartTime: parseTime("2024-09-01T12:00:00Z"),
+				EndTime:   parseTime("2024-09-01T13:00:00Z"),
+			},
+		},
+	}
+
+	slots, err := RankSlots(req, events, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(slots) != 3 {
+		t.Fatalf("Expected 3 slots, got %d", len(slots))
+	}
+
+	best, _ := FindOptimalSlot(req, events)
+	if !slots[0].Start.Equal(best.Start) {
+		t.Errorf("Expected the top ranked slot %v to match FindOptimalSlot %v", slots[0].Start, best.Start)
+	}
+
+	for i, slot := range slots {
+		if i > 0 && slot.Score > slots[i-1].Score {
+			t.Errorf("Slots are not ordered by score: %v > %v", slot.Score, slots[i-1].Score)
+		}
+
+		if len(slot.Breakdown) != 4 {
+			t.Fatalf("Expected 4 criteria in the breakdown, got %d", len(slot.Breakdown))
+		}
+		var total float64
+		for _, criterion := range slot.Breakdown {
+			total += criterion.Weighted
+			if len(criterion.Participants) != 1 || criterion.Participants[0].UserID != "user1" {
+				t.Fatalf("Expected a score for user1 under %s, got %v", criterion.Name, criterion.Participants)
+			}
+		}
+		if math.Abs(total-slot.Score) > 1e-9 {
+			t.Errorf("Breakdown sums to %v but score is %v", total, slot.Score)
+		}
+	}
+
+	all, _ := RankSlots(req, events, 0)
+	if len(all) <= 3 {
+		t.Errorf("Expected a limit of 0 to return every available slot, got %d", len(all))
+	}
+}
+
+func TestParticipantBreakdown(t *testing.T) {
+	start := time.Date(2024, 9, 2, 1, 0, 0, 0, time.UTC) // 10 AM in Tokyo, 9 PM in New York
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"tokyo", "nyc"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: start, End: start.Add(time.Hour)},
+	}
+	events := map[string][]domain.CalendarEvent{
+		"tokyo": {},
+		"nyc": {
+			{
+				StartTime: start.Add(-10 * time.Minute),
+				EndTime:   start.Add(-5 * time.Minute),
+			},
+		},
+	}
+
+	slot, err := FindOptimalSlot(req, events, WithParticipants(
+		domain.User{ID: "tokyo", TimeZone: "Asia/Tokyo"},
+		domain.User{ID: "nyc", TimeZone: "America/New_York"},
+	))
+	if err != nil || slot == nil {
+		t.Fatalf("Expected a slot, got %v (%v)", slot, err)
+	}
+
+	scores := make(map[string]map[string]float64)
+	for _, criterion := range slot.Breakdown {
+		scores[criterion.Name] = make(map[string]float64)
+		var total float64
+		for _, p := range criterion.Participants {
+			scores[criterion.Name][p.UserID] = p.Score
+			total += p.Score
+		}
+		if avg := total / float64(len(criterion.Participants)); math.Abs(avg-criterion.Score) > 1e-9 {
+			t.Errorf("Expected %s to be the participants' average %v, got %v", criterion.Name, avg, criterion.Score)
+		}
+	}
+
+	if scores[CriterionWorkingHours]["tokyo"] != 1.0 || scores[CriterionWorkingHours]["nyc"] != 0.0 {
+		t.Errorf("Expected only Tokyo to be within working hours, got %v", scores[CriterionWorkingHours])
+	}
+	if scores[CriterionBufferTime]["tokyo"] != 1.0 || scores[CriterionBufferTime]["nyc"] >= 1.0 {
+		t.Errorf("Expected only New York to lack buffer time, got %v", scores[CriterionBufferTime])
+	}
+}
+
+func TestParticipantTimeZones(t *testing.T) {
+	parseTime := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+
+	tokyo := domain.User{ID: "tokyo", TimeZone: "Asia/Tokyo"}
+	newYork := domain.User{ID: "nyc", TimeZone: "America/New_York", WorkdayStart: 8, WorkdayEnd: 16}
+
+	t.Run("Working hours are evaluated in local time", func(t *testing.T) {
+		o := newOptions([]Option{WithParticipants(tokyo, newYork)})
+		slot := TimeSlot{
+			Start: parseTime("2024-09-02T01:00:00Z"), // 10 AM in Tokyo, 9 PM in New York
+			End:   parseTime("2024-09-02T02:00:00Z"),
+		}
+
+		if score := workingHoursScore(slot, o.participants["tokyo"]); score != 1.0 {
+			t.Errorf("Expected Tokyo working hours score 1.0, got %v", score)
+		}
+		if score := workingHoursScore(slot, o.participants["nyc"]); score != 0.0 {
+			t.Errorf("Expected New York working hours score 0.0, got %v", score)
+		}
+	})
+
+	t.Run("Custom working hours", func(t *testing.T) {
+		o := newOptions([]Option{WithParticipants(newYork)})
+		slot := TimeSlot{
+			Start: parseTime("2024-09-02T12:00:00Z"), // 8 AM in New York
+			End:   parseTime("2024-09-02T13:00:00Z"),
+		}
+
+		if score := earlySlotScore(slot, o.participants["nyc"]); score != 1.0 {
+			t.Errorf("Expected the start of the custom working day to score 1.0, got %v", score)
+		}
+	})
+
+	t.Run("Optimal slot follows the participant's time zone", func(t *testing.T) {
+		req := domain.ScheduleRequest{
+			ParticipantIDs:  []string{"tokyo"},
+			DurationMinutes: 60,
+			TimeRange: domain.TimeRange{
+				Start: parseTime("2024-09-01T20:00:00Z"),
+				End:   parseTime("2024-09-02T12:00:00Z"),
+			},
+		}
+
+		slot, err := FindOptimalSlot(req, map[string][]domain.CalendarEvent{}, WithParticipants(tokyo))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := parseTime("2024-09-02T00:00:00Z") // 9 AM in Tokyo
+		if !slot.Start.Equal(expected) {
+			t.Errorf("Expected start time %v, got %v", expected, slot.Start)
+		}
+	})
+}
+
+func TestIsSlotAvailable(t *testing.T) {
+	parseTime := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			{
+				StartTime: parseTime("2024-09-01T10:00:00Z"),
+				EndTime:   parseTime("2024-09-01T11:00:00Z"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		expected bool
+	}{
+		{"Ends when the event starts", "2024-09-01T09:00:00Z", "2024-09-01T10:00:00Z", true},
+		{"Starts when the event ends", "2024-09-01T11:00:00Z", "2024-09-01T12:00:00Z", true},
+		{"Overlaps the event start", "2024-09-01T09:30:00Z", "2024-09-01T10:30:00Z", false},
+		{"Overlaps the event end", "2024-09-01T10:30:00Z", "2024-09-01T11:30:00Z", false},
+		{"Inside the event", "2024-09-01T10:15:00Z", "2024-09-01T10:45:00Z", false},
+		{"Contains the event", "2024-09-01T09:00:00Z", "2024-09-01T12:00:00Z", false},
+		{"Same as the event", "2024-09-01T10:00:00Z", "2024-09-01T11:00:00Z", false},
+		{"Well before the event", "2024-09-01T07:00:00Z", "2024-09-01T08:00:00Z", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available := IsSlotAvailable(parseTime(tt.start), parseTime(tt.end), events)
+			if available != tt.expected {
+				t.Errorf("Expected available=%v, got %v", tt.expected, available)
+			}
+		})
+	}
+}
+
+func TestMergeBusyAndFreeIntervals(t *testing.T) {
+	parseTime := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	event := func(start, end string) domain.CalendarEvent {
+		return domain.CalendarEvent{StartTime: parseTime(start), EndTime: parseTime(end)}
+	}
+
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			event("2024-09-01T13:00:00Z", "2024-09-01T14:00:00Z"),
+			event("2024-09-01T09:30:00Z", "2024-09-01T10:30:00Z"),
+		},
+		"user2": {
+			event("2024-09-01T10:00:00Z", "2024-09-01T11:00:00Z"), // overlaps user1
+			event("2024-09-01T11:00:00Z", "2024-09-01T11:30:00Z"), // touches the previous one
+			event("2024-09-01T15:00:00Z", "2024-09-01T15:00:00Z"), // empty
+			event("2024-09-01T16:30:00Z", "2024-09-01T18:00:00Z"), // runs past the window
+		},
+	}
+
+	busy := MergeBusy(events)
+	expectedBusy := []Interval{
+		{Start: parseTime("2024-09-01T09:30:00Z"), End: parseTime("2024-09-01T11:30:00Z")},
+		{Start: parseTime("2024-09-01T13:00:00Z"), End: parseTime("2024-09-01T14:00:00Z")},
+		{Start: parseTime("2024-09-01T16:30:00Z"), End: parseTime("2024-09-01T18:00:00Z")},
+	}
+	if len(busy) != len(expectedBusy) {
+		t.Fatalf("Expected busy intervals %v, got %v", expectedBusy, busy)
+	}
+	for i := range busy {
+		if !busy[i].Start.Equal(expectedBusy[i].Start) || !busy[i].End.Equal(expectedBusy[i].End) {
+			t.Errorf("Expected busy interval %v, got %v", expectedBusy[i], busy[i])
+		}
+	}
+
+	free := FreeIntervals(busy, parseTime("2024-09-01T09:00:00Z"), parseTime("2024-09-01T17:00:00Z"))
+	expectedFree := []Interval{
+		{Start: parseTime("2024-09-01T09:00:00Z"), End: parseTime("2024-09-01T09:30:00Z")},
+		{Start: parseTime("2024-09-01T11:30:00Z"), End: parseTime("2024-09-01T13:00:00Z")},
+		{Start: parseTime("2024-09-01T14:00:00Z"), End: parseTime("2024-09-01T16:30:00Z")},
+	}
+	if len(free) != len(expectedFree) {
+		t.Fatalf("Expected free intervals %v, got %v", expectedFree, free)
+	}
+	for i := range free {
+		if !free[i].Start.Equal(expectedFree[i].Start) || !free[i].End.Equal(expectedFree[i].End) {
+			t.Errorf("Expected free interval %v, got %v", expectedFree[i], free[i])
+		}
+	}
+
+	if free := FreeIntervals(nil, parseTime("2024-09-01T09:00:00Z"), parseTime("2024-09-01T17:00:00Z")); len(free) != 1 {
+		t.Errorf("Expected the whole window to be free without events, got %v", free)
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	parseTime := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	event := func(start, end string) domain.CalendarEvent {
+		return domain.CalendarEvent{Title: "Private", StartTime: parseTime(start), EndTime: parseTime(end)}
+	}
+
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			event("2024-09-01T08:00:00Z", "2024-09-01T09:30:00Z"), // starts before the window
+			event("2024-09-01T09:30:00Z", "2024-09-01T10:00:00Z"), // touches the previous one
+		},
+		"user2": {
+			event("2024-09-01T12:00:00Z", "2024-09-01T13:00:00Z"),
+			event("2024-09-01T18:00:00Z", "2024-09-01T19:00:00Z"), // outside the window
+		},
+		"user3": nil,
+	}
+	start, end := parseTime("2024-09-01T09:00:00Z"), parseTime("2024-09-01T17:00:00Z")
+
+	busy, free := FreeBusy(events, start, end)
+
+	expectedBusy := map[string][]Interval{
+		"user1": {{Start: start, End: parseTime("2024-09-01T10:00:00Z")}},
+		"user2": {{Start: parseTime("2024-09-01T12:00:00Z"), End: parseTime("2024-09-01T13:00:00Z")}},
+		"user3": {},
+	}
+	for userID, expected := range expectedBusy {
+		if len(busy[userID]) != len(expected) {
+			t.Errorf("Expected %s busy during %v, got %v", userID, expected, busy[userID])
+			continue
+		}
+		for i := range expected {
+			if !busy[userID][i].Start.Equal(expected[i].Start) || !busy[userID][i].End.Equal(expected[i].End) {
+				t.Errorf("Expected %s busy during %v, got %v", userID, expected[i], busy[userID][i])
+			}
+		}
+	}
+
+	expectedFree := []Interval{
+		{Start: parseTime("2024-09-01T10:00:00Z"), End: parseTime("2024-09-01T12:00:00Z")},
+		{Start: parseTime("2024-09-01T13:00:00Z"), End: end},
+	}
+	if len(free) != len(expectedFree) {
+		t.Fatalf("Expected free intervals %v, got %v", expectedFree, free)
+	}
+	for i := range free {
+		if !free[i].Start.Equal(expectedFree[i].Start) || !free[i].End.Equal(expectedFree[i].End) {
+			t.Errorf("Expected free interval %v, got %v", expectedFree[i], free[i])
+		}
+	}
+
+	// Travel time is busy, even when the event itself lies outside the window
+	travel := event("2024-09-01T17:30:00Z", "2024-09-01T18:00:00Z")
+	travel.TravelMinutes = 45
+	busy, free = FreeBusy(map[string][]domain.CalendarEvent{"user1": {travel}}, start, end)
+	if len(busy["user1"]) != 1 || !busy["user1"][0].Start.Equal(parseTime("2024-09-01T16:45:00Z")) || !busy["user1"][0].End.Equal(end) {
+		t.Errorf("Expected user1 busy from 16:45 for the travel time, got %v", busy["user1"])
+	}
+	if len(free) != 1 || !free[0].End.Equal(parseTime("2024-09-01T16:45:00Z")) {
+		t.Errorf("Expected to be free until the travel time starts, got %v", free)
+	}
+}
+
+// The functions below are the original step-and-scan implementations. They
+// serve as a reference for the sweep-line engine and as a benchmark baseline.
+
+func naiveAvailableSlots(req domain.ScheduleRequest, events map[string][]domain.CalendarEvent) []TimeSlot {
+	var slots []TimeSlot
+	step := time.Duration(domain.DefaultGranularityMinutes) * time.Minute
+	for current := req.TimeRange.Start; current.Before(req.TimeRange.End); current = current.Add(step) {
+		slotEnd := current.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		if !slotEnd.After(req.TimeRange.End) && IsSlotAvailable(current, slotEnd, events) {
+			slots = append(slots, TimeSlot{Start: current, End: slotEnd})
+		}
+	}
+	return slots
+}
+
+func naiveGapMinimizationScore(slot TimeSlot, events map[string][]domain.CalendarEvent) float64 {
+	var totalScore float64
+	for _, userEvents := range events {
+		score := 1.0
+		for _, event := range userEvents {
+			if !event.EndTime.After(slot.Start) {
+				gap := slot.Start.Sub(event.EndTime).Minutes()
+				if gap > 0 && gap < desiredBufferTime {
+					score *= 0.5
+				} else if gap > 60 {
+					score *= 0.8
+				}
+			}
+			if !event.StartTime.Before(slot.End) {
+				gap := event.StartTime.Sub(slot.End).Minutes()
+				if gap > 0 && gap < desiredBufferTime {
+					score *= 0.5
+				} else if gap > 60 {
+					score *= 0.8
+				}
+			}
+		}
+		totalScore += score
+	}
+	if len(events) == 0 {
+		return 1.0
+	}
+	return totalScore / float64(len(events))
+}
+
+func naiveBufferTimeScore(slot TimeSlot, events map[string][]domain.CalendarEvent) float64 {
+	var totalScore float64
+	for _, userEvents := range events {
+		score := 1.0
+		for _, event := range userEvents {
+			if !event.EndTime.After(slot.Start) {
+				if before := slot.Start.Sub(event.EndTime).Minutes(); before < desiredBufferTime {
+					score *= before / desiredBufferTime
+				}
+			}
+			if !event.StartTime.Before(slot.End) {
+				if after := event.StartTime.Sub(slot.End).Minutes(); after < desiredBufferTime {
+					score *= after / desiredBufferTime
+				}
+			}
+		}
+		totalScore += score
+	}
+	if len(events) == 0 {
+		return 1.0
+	}
+	return totalScore / float64(len(events))
+}
+
+// randomCalendars generates busy calendars with a few events per participant
+// and working day, at random 5 minute offsets
+func randomCalendars(rng *rand.Rand, participants int, start time.Time, days int) map[string][]domain.CalendarEvent {
+	events := make(map[string][]domain.CalendarEvent)
+	for p := 0; p < participants; p++ {
+		id := fmt.Sprintf("user%d", p)
+		events[id] = []domain.CalendarEvent{}
+		for d := 0; d < days; d++ {
+			day := start.AddDate(0, 0, d)
+			for n := rng.Intn(4); n > 0; n-- {
+				eventStart := day.Add(time.Duration(7*12+rng.Intn(12*12)) * 5 * time.Minute)
+				eventEnd := eventStart.Add(time.Duration(3+rng.Intn(22)) * 5 * time.Minute)
+				events[id] = append(events[id], domain.CalendarEvent{StartTime: eventStart, EndTime: eventEnd})
+			}
+		}
+	}
+	return events
+}
+
+func TestSweepMatchesNaiveScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	start := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 20; i++ {
+		events := randomCalendars(rng, 1+rng.Intn(4), start, 7)
+		req := domain.ScheduleRequest{
+			DurationMinutes: 15 * (1 + rng.Intn(8)),
+			TimeRange: domain.TimeRange{
+				Start: start.Add(time.Duration(rng.Intn(60)) * time.Minute),
+				End:   start.AddDate(0, 0, 7),
+			},
+		}
+
+		expected := naiveAvailableSlots(req, events)
+		slots := findAvailableSlots(req, events)
+		if len(slots) != len(expected) {
+			t.Fatalf("Run %d: expected %d slots, got %d", i, len(expected), len(slots))
+		}
+
+		indexes := newEventIndexes(events)
+		for j := range slots {
+			if !slots[j].Start.Equal(expected[j].Start) || !slots[j].End.Equal(expected[j].End) {
+				t.Fatalf("Run %d: expected slot %v-%v, got %v-%v", i, expected[j].Start, expected[j].End, slots[j].Start, slots[j].End)
+			}
+			if got, want := gapMinimizationScore(slots[j], indexes), naiveGapMinimizationScore(slots[j], events); math.Abs(got-want) > 1e-9 {
+				t.Fatalf("Run %d: gap score for %v is %v, expected %v", i, slots[j].Start, got, want)
+			}
+			if got, want := bufferTimeScore(slots[j], indexes), naiveBufferTimeScore(slots[j], events); math.Abs(got-want) > 1e-9 {
+				t.Fatalf("Run %d: buffer score for %v is %v, expected %v", i, slots[j].Start, got, want)
+			}
+		}
+	}
+}
+
+// yearLongRequest searches a one-year window, the longest that the service allows
+func yearLongRequest(b *testing.B) (domain.ScheduleRequest, map[string][]domain.CalendarEvent) {
+	b.Helper()
+	start := time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC)
+	events := randomCalendars(rand.New(rand.NewSource(1)), 5, start, 365)
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user0", "user1", "user2", "user3", "user4"},
+		DurationMinutes: 60,
+		TimeRange: domain.TimeRange{
+			Start: start,
+			End:   start.AddDate(1, 0, 0),
+		},
+	}
+	return req, events
+}
+
+func BenchmarkFindAvailableSlots(b *testing.B) {
+	req, events := yearLongRequest(b)
+
+	b.Run("sweep", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			findAvailableSlots(req, events)
+		}
+	})
+
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			naiveAvailableSlots(req, events)
+		}
+	})
+}
+
+func BenchmarkScoreSlots(b *testing.B) {
+	req, events := yearLongRequest(b)
+	slots := findAvailableSlots(req, events)
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			indexes := newEventIndexes(events)
+			for _, slot := range slots {
+				gapMinimizationScore(slot, indexes)
+				bufferTimeScore(slot, indexes)
+			}
+		}
+	})
+
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, slot := range slots {
+				naiveGapMinimizationScore(slot, events)
+				naiveBufferTimeScore(slot, events)
+			}
+		}
+	})
+}
+
+func BenchmarkFindOptimalSlot(b *testing.B) {
+	req, events := yearLongRequest(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := FindOptimalSlot(req, events); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSlotGrid(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+	start := time.Date(2024, 9, 2, 9, 7, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		start         time.Time
+		granularity   int
+		alignment     string
+		expectedStart time.Time
+		expectedStep  time.Duration
+	}{
+		{"Default is unaligned 15 minutes", start, 0, "", start, 15 * time.Minute},
+		{"Unaligned custom granularity", start, 10, domain.AlignNone, start, 10 * time.Minute},
+		{"Aligned to the granularity", start, 15, domain.AlignGranularity, time.Date(2024, 9, 2, 9, 15, 0, 0, time.UTC), 15 * time.Minute},
+		{"Aligned to 5 minutes", start, 5, domain.AlignGranularity, time.Date(2024, 9, 2, 9, 10, 0, 0, time.UTC), 5 * time.Minute},
+		{"Aligned to the half hour", start, 15, domain.AlignHalfHour, time.Date(2024, 9, 2, 9, 30, 0, 0, time.UTC), 30 * time.Minute},
+		{"Half hour alignment with hourly granularity", start, 60, domain.AlignHalfHour, time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC), time.Hour},
+		{"Aligned to the hour", start, 15, domain.AlignHour, time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC), time.Hour},
+		{"Already aligned", time.Date(2024, 9, 2, 9, 30, 0, 0, time.UTC), 30, domain.AlignGranularity, time.Date(2024, 9, 2, 9, 30, 0, 0, time.UTC), 30 * time.Minute},
+		{"Aligned in the start's location", time.Date(2024, 9, 2, 9, 7, 0, 0, kolkata), 15, domain.AlignHour, time.Date(2024, 9, 2, 10, 0, 0, 0, kolkata), time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anchor, step := slotGrid(domain.ScheduleRequest{
+				TimeRange:          domain.TimeRange{Start: tt.start, End: tt.start.Add(8 * time.Hour)},
+				GranularityMinutes: tt.granularity,
+				Alignment:          tt.alignment,
+			})
+			if !anchor.Equal(tt.expectedStart) {
+				t.Errorf("Expected first candidate at %v, got %v", tt.expectedStart, anchor)
+			}
+			if step != tt.expectedStep {
+				t.Errorf("Expected step %v, got %v", tt.expectedStep, step)
+			}
+		})
+	}
+}
+
+func TestAlignedSlotsAvoidOddMinutes(t *testing.T) {
+	start := time.Date(2024, 9, 2, 9, 7, 0, 0, time.UTC)
+	req := domain.ScheduleRequest{
+		ParticipantIDs:     []string{"user1"},
+		DurationMinutes:    30,
+		TimeRange:          domain.TimeRange{Start: start, End: start.Add(4 * time.Hour)},
+		GranularityMinutes: 10,
+		Alignment:          domain.AlignHalfHour,
+	}
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			{
+				StartTime: time.Date(2024, 9, 2, 9, 30, 0, 0, time.UTC),
+				EndTime:   time.Date(2024, 9, 2, 10, 10, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	slots := findAvailableSlots(req, events)
+	if len(slots) == 0 {
+		t.Fatal("Expected available slots")
+	}
+	for _, slot := range slots {
+		if slot.Start.Minute()%30 != 0 {
+			t.Errorf("Expected slots on the full or half hour, got %v", slot.Start)
+		}
+	}
+	if expected := time.Date(2024, 9, 2, 10, 30, 0, 0, time.UTC); !slots[0].Start.Equal(expected) {
+		t.Errorf("Expected the first slot after the event at %v, got %v", expected, slots[0].Start)
+	}
+}
+
+func TestScoringStrategies(t *testing.T) {
+	start := time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: start, End: start.Add(8 * time.Hour)},
+	}
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			{
+				StartTime: time.Date(2024, 9, 2, 14, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2024, 9, 2, 15, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	registry := NewRegistry()
+
+	tests := []struct {
+		strategy string
+		check    func(slot *TimeSlot) bool
+		expected string
+	}{
+		{StrategyEarliest, func(s *TimeSlot) bool { return s.Start.Equal(start) }, "the start of the day"},
+		{StrategyAfternoonPreferred, func(s *TimeSlot) bool { return s.Start.Hour() >= 12 }, "an afternoon slot"},
+		{StrategyCompactDay, func(s *TimeSlot) bool {
+			busyStart := time.Date(2024, 9, 2, 14, 0, 0, 0, time.UTC)
+			busyEnd := busyStart.Add(time.Hour)
+			gap := busyStart.Sub(s.End)
+			if s.Start.After(busyStart) {
+				gap = s.Start.Sub(busyEnd)
+			}
+			return gap >= desiredBufferTime*time.Minute && gap <= time.Hour
+		}, "a slot within an hour of the existing event"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			scorer, ok := registry.Get(tt.strategy)
+			if !ok {
+				t.Fatalf("Expected strategy %q to be registered", tt.strategy)
+			}
+			slot, err := FindOptimalSlot(req, events, WithScorer(scorer))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.check(slot) {
+				t.Errorf("Expected %s, got %v-%v", tt.expected, slot.Start, slot.End)
+			}
+		})
+	}
+
+	t.Run("Default strategy", func(t *testing.T) {
+		scorer, ok := registry.Get("")
+		if !ok {
+			t.Fatal("Expected an empty name to select the default strategy")
+		}
+		if len(scorer.(WeightedScorer).Weights) != 4 {
+			t.Errorf("Expected the default strategy to use 4 criteria, got %v", scorer)
+		}
+	})
+
+	t.Run("Weight overrides", func(t *testing.T) {
+		registry := NewRegistry()
+		overrides, err := ParseWeightOverrides(`{"earliest": {"earlySlot": 5}, "team-a": {"afternoon": 1}}`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := registry.OverrideWeights(overrides); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		earliest, _ := registry.Get(StrategyEarliest)
+		weights := earliest.(WeightedScorer).Weights
+		if weights[CriterionEarlySlot] != 5 || weights[CriterionWorkingHours] != 1 {
+			t.Errorf("Expected earlySlot to be overridden and other weights kept, got %v", weights)
+		}
+		if _, ok := registry.Get("team-a"); !ok {
+			t.Error("Expected an override for an unknown name to register a new strategy")
+		}
+		if _, ok := NewRegistry().Get("team-a"); ok {
+			t.Error("Expected overrides not to leak into other registries")
+		}
+	})
+
+	t.Run("Invalid overrides", func(t *testing.T) {
+		for _, overrides := range []map[string]Weights{
+			{StrategyEarliest: {"lunch": 1}},
+			{StrategyEarliest: {CriterionEarlySlot: -1}},
+		} {
+			if err := NewRegistry().OverrideWeights(overrides); err == nil {
+				t.Errorf("Expected %v to be rejected", overrides)
+			}
+		}
+	})
+}
+
+func TestGapMinimizationScore(t *testing.T) {
+	slotStart := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	slot := TimeSlot{Start: slotStart, End: slotStart.Add(time.Hour)}
+	at := func(minutes int) time.Time { return slotStart.Add(time.Duration(minutes) * time.Minute) }
+
+	tests := []struct {
+		name     string
+		event    domain.CalendarEvent
+		expected float64
+	}{
+		{"ends at the slot start", domain.CalendarEvent{StartTime: at(-60), EndTime: at(0)}, 1.0},
+		{"starts at the slot end", domain.CalendarEvent{StartTime: at(60), EndTime: at(120)}, 1.0},
+		{"small gap before", domain.CalendarEvent{StartTime: at(-60), EndTime: at(-5)}, 0.5},
+		{"small gap after", domain.CalendarEvent{StartTime: at(70), EndTime: at(120)}, 0.5},
+		{"desired buffer before", domain.CalendarEvent{StartTime: at(-60), EndTime: at(-15)}, 1.0},
+		{"large gap before", domain.CalendarEvent{StartTime: at(-180), EndTime: at(-90)}, 0.8},
+		{"large gap after", domain.CalendarEvent{StartTime: at(150), EndTime: at(180)}, 0.8},
+	}
+
+	for _, tt := range tests {
+		idx := newEventIndex("user1", []domain.CalendarEvent{tt.event})
+		if score := gapMinimizationScoreFor(slot, idx); score != tt.expected {
+			t.Errorf("%s: expected score %v, got %v", tt.name, tt.expected, score)
+		}
+	}
+}
+
+func TestAfternoonScore(t *testing.T) {
+	tests := []struct {
+		hour     int
+		expected float64
+	}{
+		{7, 0.0},
+		{9, 0.25},
+		{11, 0.75},
+		{12, 1.0},
+		{16, 1.0},
+		{17, 0.0},
+	}
+
+	for _, tt := range tests {
+		start := time.Date(2024, 9, 2, tt.hour, 0, 0, 0, time.UTC)
+		slot := TimeSlot{Start: start, End: start.Add(time.Hour)}
+		if score := afternoonScore(slot, defaultParticipant); score != tt.expected {
+			t.Errorf("Expected score %v at %d:00, got %v", tt.expected, tt.hour, score)
+		}
+	}
+}
+
+func TestOptionalParticipants(t *testing.T) {
+	start := time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return start.Add(time.Duration(hour-9) * time.Hour) }
+	busy := func(from, to int) []domain.CalendarEvent {
+		return []domain.CalendarEvent{{StartTime: at(from), EndTime: at(to)}}
+	}
+	events := map[string][]domain.CalendarEvent{
+		"req":  busy(9, 10),
+		"opt1": busy(10, 12),
+		"opt2": busy(13, 17),
+		"opt3": busy(9, 17),
+	}
+	req := domain.ScheduleRequest{
+		ParticipantIDs:         []string{"req"},
+		OptionalParticipantIDs: []string{"opt1", "opt2", "opt3"},
+		DurationMinutes:        60,
+		TimeRange:              domain.TimeRange{Start: start, End: at(17)},
+		Alignment:              domain.AlignHour,
+	}
+
+	t.Run("Most optional participants attend", func(t *testing.T) {
+		slot, err := FindOptimalSlot(req, events)
+		if err != nil || slot == nil {
+			t.Fatalf("Expected a slot, got %v (%v)", slot, err)
+		}
+		if !slot.Start.Equal(at(12)) {
+			t.Errorf("Expected the only slot free for opt1 and opt2 at noon, got %v", slot.Start)
+		}
+		if len(slot.Unavailable) != 1 || slot.Unavailable[0] != "opt3" {
+			t.Errorf("Expected only opt3 to be unavailable, got %v", slot.Unavailable)
+		}
+	})
+
+	t.Run("Busy required participant", func(t *testing.T) {
+		slots, _ := RankSlots(req, events, 0)
+		for _, slot := range slots {
+			if slot.Start.Before(at(10)) {
+				t.Errorf("Expected no slot while the required participant is busy, got %v", slot.Start)
+			}
+		}
+	})
+
+	t.Run("Quorum", func(t *testing.T) {
+		quorum := req
+		quorum.MinAttendees = 3
+		slots, _ := RankSlots(quorum, events, 0)
+		if len(slots) != 1 || !slots[0].Start.Equal(at(12)) {
+			t.Errorf("Expected only the noon slot to reach a quorum of 3, got %v", slots)
+		}
+
+		quorum.MinAttendees = 4
+		if slot, _ := FindOptimalSlot(quorum, events); slot != nil {
+			t.Errorf("Expected no slot that all 4 participants can attend, got %v", slot.Start)
+		}
+	})
+}
+
+func TestRecurringSlots(t *testing.T) {
+	monday := time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: monday, End: monday.Add(3 * time.Hour)},
+		Alignment:       domain.AlignHour,
+		Recurrence:      "FREQ=WEEKLY;COUNT=4",
+	}
+	// Busy at 9 AM in the second week, and at 10 AM in the third and fourth
+	events := map[string][]domain.CalendarEvent{
+		"user1": {
+			{StartTime: monday.AddDate(0, 0, 7), EndTime: monday.AddDate(0, 0, 7).Add(time.Hour)},
+			{StartTime: monday.AddDate(0, 0, 14).Add(time.Hour), EndTime: monday.AddDate(0, 0, 14).Add(2 * time.Hour)},
+			{StartTime: monday.AddDate(0, 0, 21).Add(time.Hour), EndTime: monday.AddDate(0, 0, 21).Add(2 * time.Hour)},
+		},
+	}
+	rule, err := recurrence.Parse(req.Recurrence)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Every occurrence must be free", func(t *testing.T) {
+		slot, err := FindOptimalSlot(req, events, WithRecurrence(rule, 0, nil))
+		if err != nil || slot == nil {
+			t.Fatalf("Expected a slot, got %v (%v)", slot, err)
+		}
+		if expected := monday.Add(2 * time.Hour); !slot.Start.Equal(expected) || len(slot.Skipped) != 0 {
+			t.Errorf("Expected the only fully free series at %v, got %v skipping %v", expected, slot.Start, slot.Skipped)
+		}
+	})
+
+	t.Run("Threshold allows skipped occurrences", func(t *testing.T) {
+		slots, _ := RankSlots(req, events, 0, WithRecurrence(rule, 0.75, nil))
+		if len(slots) != 2 {
+			t.Fatalf("Expected the 9 and 11 AM series, got %v", slots)
+		}
+		for _, slot := range slots {
+			if slot.Start.Equal(monday) {
+				if len(slot.Skipped) != 1 || !slot.Skipped[0].Equal(monday.AddDate(0, 0, 7)) {
+					t.Errorf("Expected the second 9 AM occurrence to be skipped, got %v", slot.Skipped)
+				}
+			}
+		}
+	})
+
+	t.Run("Occurrences keep their local time in the series' time zone", func(t *testing.T) {
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		if err != nil {
+			t.Skipf("Time zone data unavailable: %v", err)
+		}
+		// 9 AM in Berlin, given as a fixed offset; the third occurrence falls
+		// after the switch to summer time, at 7 AM UTC
+		start := time.Date(2024, 3, 18, 9, 0, 0, 0, time.FixedZone("", 3600))
+		req := domain.ScheduleRequest{
+			ParticipantIDs:  []string{"user1"},
+			DurationMinutes: 60,
+			TimeRange:       domain.TimeRange{Start: start, End: start.Add(time.Hour)},
+			Recurrence:      "FREQ=WEEKLY;COUNT=3",
+		}
+		third := time.Date(2024, 4, 1, 7, 0, 0, 0, time.UTC)
+		events := map[string][]domain.CalendarEvent{
+			"user1": {{StartTime: third, EndTime: third.Add(time.Hour)}},
+		}
+		rule, err := recurrence.Parse(req.Recurrence)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		slot, err := FindOptimalSlot(req, events, WithRecurrence(rule, 0.5, berlin))
+		if err != nil || slot == nil {
+			t.Fatalf("Expected a slot, got %v (%v)", slot, err)
+		}
+		if len(slot.Skipped) != 1 || !slot.Skipped[0].Equal(third) {
+			t.Errorf("Expected the occurrence at %v to be skipped, got %v", third, slot.Skipped)
+		}
+	})
+}
+
+func TestBuffersAndTravelTime(t *testing.T) {
+	start := time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return start.Add(time.Duration(hour-9)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	events := map[string][]domain.CalendarEvent{
+		"user1": {{StartTime: at(10, 0), EndTime: at(11, 0)}},
+		"user2": {{StartTime: at(13, 0), EndTime: at(14, 0), TravelMinutes: 30}},
+	}
+	req := domain.ScheduleRequest{
+		ParticipantIDs:     []string{"user1", "user2"},
+		DurationMinutes:    60,
+		TimeRange:          domain.TimeRange{Start: at(9, 0), End: at(17, 0)},
+		GranularityMinutes: 15,
+	}
+	starts := func(slots []TimeSlot) map[time.Time]bool {
+		found := make(map[time.Time]bool, len(slots))
+		for _, slot := range slots {
+			found[slot.Start] = true
+		}
+		return found
+	}
+
+	t.Run("Travel time blocks the slots next to an event", func(t *testing.T) {
+		found := starts(mustRank(t, req, events))
+		if found[at(12, 0)] || found[at(14, 15)] {
+			t.Errorf("Expected no slot during user2's travel time")
+		}
+		if !found[at(11, 0)] || !found[at(11, 30)] || !found[at(14, 30)] {
+			t.Errorf("Expected slots right outside the travel time")
+		}
+	})
+
+	t.Run("Request buffers", func(t *testing.T) {
+		buffered := req
+		buffered.BufferBeforeMinutes = 15
+		buffered.BufferAfterMinutes = 10
+		found := starts(mustRank(t, buffered, events))
+		if found[at(9, 0)] || found[at(11, 0)] {
+			t.Errorf("Expected no slot without buffers around user1's event")
+		}
+		if !found[at(11, 15)] || found[at(11, 30)] {
+			t.Errorf("Expected 11:15 to be the only slot between the events")
+		}
+	})
+
+	t.Run("Participant buffers", func(t *testing.T) {
+		user := domain.User{ID: "user1", BufferBeforeMinutes: 30}
+		found := starts(mustRank(t, req, events, WithParticipants(user)))
+		if found[at(11, 0)] || found[at(11, 15)] || !found[at(11, 30)] {
+			t.Errorf("Expected user1's buffer to keep 11:00 and 11:15 free")
+		}
+		if !found[at(9, 0)] {
+			t.Errorf("Expected no buffer after the meeting for user1")
+		}
+	})
+
+	t.Run("Buffers a meeting was booked with", func(t *testing.T) {
+		booked := map[string][]domain.CalendarEvent{
+			"user1": {{StartTime: at(10, 0), EndTime: at(11, 0), BufferBeforeMinutes: 15, BufferAfterMinutes: 30}},
+			"user2": events["user2"],
+		}
+		found := starts(mustRank(t, req, booked))
+		if found[at(9, 0)] || found[at(11, 0)] || found[at(11, 15)] || !found[at(11, 30)] {
+			t.Errorf("Expected the booked meeting's buffers to keep 9:45 to 11:30 free")
+		}
+
+		buffered := req
+		buffered.DurationMinutes = 30
+		buffered.BufferBeforeMinutes = 45
+		found = starts(mustRank(t, buffered, booked))
+		if found[at(11, 30)] || !found[at(11, 45)] {
+			t.Errorf("Expected the longer of the buffers to apply")
+		}
+		if IsSlotAvailable(at(11, 0), at(11, 30), booked) {
+			t.Errorf("Expected the slot during the buffer to be unavailable")
+		}
+	})
+
+	t.Run("IsSlotAvailable counts travel time", func(t *testing.T) {
+		if IsSlotAvailable(at(14, 0), at(14, 30), events) {
+			t.Errorf("Expected the slot during travel time to be unavailable")
+		}
+		if !IsSlotAvailable(at(14, 30), at(15, 0), events) {
+			t.Errorf("Expected the slot after travel time to be available")
+		}
+	})
+}
+
+func mustRank(t *testing.T, req domain.ScheduleRequest, events map[string][]domain.CalendarEvent, opts ...Option) []TimeSlot {
+	t.Helper()
+	slots, err := RankSlots(req, events, 0, opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return slots
+}
+
+func TestResources(t *testing.T) {
+	start := time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return start.Add(time.Duration(hour-9) * time.Hour) }
+	events := map[string][]domain.CalendarEvent{
+		"user1":    {{StartTime: at(9), EndTime: at(10)}},
+		"room-a":   {{StartTime: at(10), EndTime: at(11)}},
+		"room-b":   {{StartTime: at(10), EndTime: at(12)}},
+		"beamer-1": nil,
+	}
+	req := domain.ScheduleRequest{
+		ParticipantIDs:  []string{"user1"},
+		DurationMinutes: 60,
+		TimeRange:       domain.TimeRange{Start: start, End: at(13)},
+		Alignment:       domain.AlignHour,
+	}
+	resources := func(slots []TimeSlot) map[time.Time][]string {
+		picked := make(map[time.Time][]string, len(slots))
+		for _, slot := range slots {
+			picked[slot.Start] = slot.Resources
+		}
+		return picked
+	}
+
+	t.Run("A free resource is picked with every slot", func(t *testing.T) {
+		picked := resources(mustRank(t, req, events, WithResources([]string{"room-a", "room-b"}, []string{"beamer-1"})))
+		if _, ok := picked[at(10)]; ok {
+			t.Errorf("Expected no slot while both rooms are booked, got %v", picked)
+		}
+		if rooms := picked[at(11)]; len(rooms) != 2 || rooms[0] != "room-a" || rooms[1] != "beamer-1" {
+			t.Errorf("Expected room-a and the beamer at 11 AM, got %v", rooms)
+		}
+		if rooms := picked[at(12)]; len(rooms) != 2 || rooms[0] != "room-a" {
+			t.Errorf("Expected the preferred room-a at noon, got %v", rooms)
+		}
+	})
+
+	t.Run("Resources are not booked twice", func(t *testing.T) {
+		picked := resources(mustRank(t, req, events, WithResources([]string{"room-a", "room-b"}, []string{"room-a"})))
+		if rooms := picked[at(12)]; len(rooms) != 2 || rooms[0] != "room-b" || rooms[1] != "room-a" {
+			t.Errorf("Expected room-b to leave room-a for the second pool, got %v", rooms)
+		}
+		if _, ok := picked[at(11)]; ok {
+			t.Errorf("Expected no slot at 11 AM with room-b booked, got %v", picked[at(11)])
+		}
+	})
+
+	t.Run("Resource calendars do not count as participants", func(t *testing.T) {
+		slots := mustRank(t, req, events, WithResources([]string{"room-a", "room-b"}, []string{"beamer-1"}))
+		for _, criterion := range slots[0].Breakdown {
+			if len(criterion.Participants) != 1 {
+				t.Errorf("Expected only user1 to be scored under %s, got %v", criterion.Name, criterion.Participants)
+			}
+		}
+	})
+}
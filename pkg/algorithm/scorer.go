@@ -0,0 +1,272 @@
+package algorithm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// Names of the built-in scoring strategies
+const (
+	StrategyBalanced           = "balanced"
+	StrategyEarliest           = "earliest"
+	StrategyCompactDay         = "compact-day"
+	StrategyMaxBuffer          = "max-buffer"
+	StrategyAfternoonPreferred = "afternoon-preferred"
+)
+
+// DefaultStrategy is used when a request does not name a strategy
+const DefaultStrategy = StrategyBalanced
+
+// Scorer rates a candidate slot. It returns the contribution of every
+// criterion it considers; the slot's score is the sum of their weighted values.
+type Scorer interface {
+	Score(slot TimeSlot, p *Participants) []domain.CriterionScore
+}
+
+// Participants holds what scorers need to know about the people a slot is
+// scored for: their preferences and their calendars
+type Participants struct {
+	profiles []participant
+	indexes  []eventIndex
+}
+
+// criterion scores a single aspect of a slot between 0 (worst) and 1 (best)
+// for each participant; the criterion's score is their average
+type criterion struct {
+	name  string
+	score func(slot TimeSlot, p *Participants) []domain.ParticipantScore
+}
+
+// criteria lists every criterion a WeightedScorer can use, in the order they
+// are reported in score breakdowns
+var criteria = []criterion{
+	{CriterionWorkingHours, perProfile(workingHoursScore)},
+	{CriterionEarlySlot, perProfile(earlySlotScore)},
+	{CriterionAfternoon, perProfile(afternoonScore)},
+	{CriterionGapMinimization, perCalendar(gapMinimizationScoreFor)},
+	{CriterionBufferTime, perCalendar(bufferTimeScoreFor)},
+}
+
+// perProfile evaluates a time of day criterion in every participant's local time
+func perProfile(score func(TimeSlot, participant) float64) func(TimeSlot, *Participants) []domain.ParticipantScore {
+	return func(slot TimeSlot, p *Participants) []domain.ParticipantScore {
+		scores := make([]domain.ParticipantScore, 0, len(p.profiles))
+		for _, profile := range p.profiles {
+			scores = append(scores, domain.ParticipantScore{UserID: profile.id, Score: score(slot, profile)})
+		}
+		return scores
+	}
+}
+
+// perCalendar evaluates a criterion against every participant's calendar
+func perCalendar(score func(TimeSlot, eventIndex) float64) func(TimeSlot, *Participants) []domain.ParticipantScore {
+	return func(slot TimeSlot, p *Participants) []domain.ParticipantScore {
+		scores := make([]domain.ParticipantScore, 0, len(p.indexes))
+		for _, idx := range p.indexes {
+			scores = append(scores, domain.ParticipantScore{UserID: idx.userID, Score: score(slot, idx)})
+		}
+		return scores
+	}
+}
+
+// averageScore returns the mean of the participants' scores, or 1.0 if there
+// is nothing to score
+func averageScore(scores []domain.ParticipantScore) float64 {
+	if len(scores) == 0 {
+		return 1.0
+	}
+	var total float64
+	for _, s := range scores {
+		total += s.Score
+	}
+	return total / float64(len(scores))
+}
+
+// Weights sets how much each criterion, by name, contributes to a slot's
+// score. Criteria without a weight are not evaluated.
+type Weights map[string]float64
+
+// Validate checks that every weight refers to a known criterion and is not negative
+func (w Weights) Validate() error {
+	for name, weight := range w {
+		if !isCriterion(name) {
+			return fmt.Errorf("unknown scoring criterion %q", name)
+		}
+		if weight < 0 {
+			return fmt.Errorf("weight of %q cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+// merge returns a copy of w with the weights in overrides replacing its own
+func (w Weights) merge(overrides Weights) Weights {
+	merged := make(Weights, len(w)+len(overrides))
+	for name, weight := range w {
+		merged[name] = weight
+	}
+	for name, weight := range overrides {
+		merged[name] = weight
+	}
+	return merged
+}
+
+func isCriterion(name string) bool {
+	for _, c := range criteria {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WeightedScorer scores slots with the built-in criteria, combining them as
+// a weighted sum
+type WeightedScorer struct {
+	Weights Weights
+}
+
+// Score implements Scorer
+func (s WeightedScorer) Score(slot TimeSlot, p *Participants) []domain.CriterionScore {
+	breakdown := make([]domain.CriterionScore, 0, len(s.Weights))
+	for _, c := range criteria {
+		weight, ok := s.Weights[c.name]
+		if !ok || weight == 0 {
+			continue
+		}
+		scores := c.score(slot, p)
+		breakdown = append(breakdown, newCriterionScore(c.name, averageScore(scores), weight, scores))
+	}
+	return breakdown
+}
+
+// builtinWeights are the weights of the built-in strategies
+var builtinWeights = map[string]Weights{
+	// StrategyBalanced is the scheduler's original notion of an optimal slot
+	StrategyBalanced: {
+		CriterionWorkingHours:    1.0,
+		CriterionEarlySlot:       0.8,
+		CriterionGapMinimization: 0.6,
+		CriterionBufferTime:      0.4,
+	},
+	StrategyEarliest: {
+		CriterionWorkingHours:    1.0,
+		CriterionEarlySlot:       2.0,
+		CriterionGapMinimization: 0.2,
+		CriterionBufferTime:      0.2,
+	},
+	StrategyCompactDay: {
+		CriterionWorkingHours:    1.0,
+		CriterionEarlySlot:       0.2,
+		CriterionGapMinimization: 2.0,
+		CriterionBufferTime:      0.1,
+	},
+	StrategyMaxBuffer: {
+		CriterionWorkingHours:    1.0,
+		CriterionEarlySlot:       0.2,
+		CriterionGapMinimization: 0.2,
+		CriterionBufferTime:      2.0,
+	},
+	StrategyAfternoonPreferred: {
+		CriterionWorkingHours:    1.0,
+		CriterionAfternoon:       1.0,
+		CriterionGapMinimization: 0.6,
+		CriterionBufferTime:      0.4,
+	},
+}
+
+// DefaultScorer returns the scorer of DefaultStrategy
+func DefaultScorer() Scorer {
+	return WeightedScorer{Weights: builtinWeights[DefaultStrategy]}
+}
+
+// Registry maps strategy names to the scorers that implement them. It is
+// safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	scorers map[string]Scorer
+}
+
+// NewRegistry returns a registry holding the built-in strategies
+func NewRegistry() *Registry {
+	r := &Registry{
+		scorers: make(map[string]Scorer, len(builtinWeights)),
+	}
+	for name, weights := range builtinWeights {
+		r.scorers[name] = WeightedScorer{Weights: weights}
+	}
+	return r
+}
+
+// Register adds a strategy, replacing any strategy of the same name
+func (r *Registry) Register(name string, scorer Scorer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scorers[name] = scorer
+}
+
+// Get returns the scorer of the named strategy; an empty name selects DefaultStrategy
+func (r *Registry) Get(name string) (Scorer, bool) {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	scorer, ok := r.scorers[name]
+	return scorer, ok
+}
+
+// Names returns the names of all registered strategies in alphabetical order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.scorers))
+	for name := range r.scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OverrideWeights changes the weights of registered strategies. Weights not
+// mentioned in overrides keep their value; a name that is not registered yet
+// becomes a new strategy with just the given weights.
+func (r *Registry) OverrideWeights(overrides map[string]Weights) error {
+	for name, weights := range overrides {
+		if err := weights.Validate(); err != nil {
+			return fmt.Errorf("strategy %q: %w", name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range overrides {
+		if scorer, ok := r.scorers[name]; ok {
+			if _, ok := scorer.(WeightedScorer); !ok {
+				return fmt.Errorf("strategy %q does not use weights", name)
+			}
+		}
+	}
+	for name, weights := range overrides {
+		var base Weights
+		if scorer, ok := r.scorers[name]; ok {
+			base = scorer.(WeightedScorer).Weights
+		}
+		r.scorers[name] = WeightedScorer{Weights: base.merge(weights)}
+	}
+	return nil
+}
+
+// ParseWeightOverrides decodes weight overrides given as JSON, e.g.
+// {"compact-day": {"gapMinimization": 3}}
+func ParseWeightOverrides(data string) (map[string]Weights, error) {
+	var overrides map[string]Weights
+	if err := json.Unmarshal([]byte(data), &overrides); err != nil {
+		return nil, fmt.Errorf("invalid scoring weights: %w", err)
+	}
+	return overrides, nil
+}
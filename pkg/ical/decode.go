@@ -0,0 +1,312 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT read from an iCalendar file
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+	// AllDay is set for events whose start is a date rather than a date-time
+	AllDay bool
+	// Recurrence is the event's RRULE, without the "RRULE:" prefix
+	Recurrence string
+	// Exceptions are the EXDATEs of a recurring event
+	Exceptions []time.Time
+	// RecurrenceID is set when the event overrides a single occurrence of
+	// the recurring event with the same UID
+	RecurrenceID *time.Time
+	// Cancelled is set for events with STATUS:CANCELLED
+	Cancelled bool
+	// Transparent is set for events with TRANSP:TRANSPARENT, which do not
+	// block time
+	Transparent bool
+	// Err is set for events that cannot be read, such as events in a time
+	// zone that cannot be resolved; their times are not reliable
+	Err error
+}
+
+// property is a single content line, e.g. DTSTART;TZID=Europe/Berlin:20240902T090000
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of an iCalendar file. Floating date-times and
+// all-day dates are read in loc. Date-times with a TZID are read in the IANA
+// time zone it names, in the one a Windows time zone name maps to, or in the
+// one matching the file's VTIMEZONE for it; events whose time zone cannot be
+// resolved are returned with Err set. Components nested in events, such as
+// alarms, are ignored.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	zones := readTimezones(lines)
+
+	var (
+		events  []Event
+		current *Event
+		hasEnd  bool
+		dur     time.Duration
+		depth   int // components entered inside the current event
+		inCal   bool
+	)
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCALENDAR") && current == nil:
+			inCal = true
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT") && current == nil:
+			if !inCal {
+				return nil, fmt.Errorf("line %d: VEVENT outside of VCALENDAR", n+1)
+			}
+			current, hasEnd, dur, depth = &Event{}, false, 0, 0
+		case prop.name == "BEGIN" && current != nil:
+			depth++
+		case prop.name == "END" && current != nil && depth > 0:
+			depth--
+		case prop.name == "END" && current != nil:
+			if err := finishEvent(current, hasEnd, dur); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			events = append(events, *current)
+			current = nil
+		case current != nil && depth == 0:
+			err := setEventProperty(current, prop, loc, zones, &hasEnd, &dur)
+			switch {
+			case errors.Is(err, errUnknownTimeZone):
+				if current.Err == nil {
+					current.Err = fmt.Errorf("%s: %w", prop.name, err)
+				}
+			case err != nil:
+				return nil, fmt.Errorf("line %d: %s: %w", n+1, prop.name, err)
+			}
+		}
+	}
+	if current != nil {
+		return nil, errors.New("unterminated VEVENT")
+	}
+	if !inCal {
+		return nil, errors.New("no VCALENDAR found")
+	}
+	return events, nil
+}
+
+func setEventProperty(event *Event, prop property, loc *time.Location, zones *zones, hasEnd *bool, dur *time.Duration) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		event.UID = prop.value
+	case "SUMMARY":
+		event.Summary = unescapeText(prop.value)
+	case "DTSTART":
+		event.Start, event.AllDay, err = parseDateTime(prop, loc, zones)
+	case "DTEND":
+		event.End, _, err = parseDateTime(prop, loc, zones)
+		*hasEnd = true
+	case "DURATION":
+		*dur, err = parseDuration(prop.value)
+	case "RRULE":
+		event.Recurrence = prop.value
+	case "EXDATE":
+		for _, value := range strings.Split(prop.value, ",") {
+			exception, _, err := parseDateTime(property{name: prop.name, params: prop.params, value: value}, loc, zones)
+			if err != nil {
+				return err
+			}
+			event.Exceptions = append(event.Exceptions, exception)
+		}
+	case "RECURRENCE-ID":
+		var recurrenceID time.Time
+		if recurrenceID, _, err = parseDateTime(prop, loc, zones); err == nil {
+			event.RecurrenceID = &recurrenceID
+		}
+	case "STATUS":
+		event.Cancelled = strings.EqualFold(prop.value, "CANCELLED")
+	case "TRANSP":
+		event.Transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+	}
+	return err
+}
+
+// finishEvent checks the event's required properties and derives its end
+// from DURATION or, as RFC 5545 prescribes, from the start if neither DTEND
+// nor DURATION is given
+func finishEvent(event *Event, hasEnd bool, dur time.Duration) error {
+	if event.UID == "" {
+		return errors.New("VEVENT without UID")
+	}
+	if event.Err != nil {
+		return nil
+	}
+	if event.Start.IsZero() {
+		return fmt.Errorf("VEVENT %s without DTSTART", event.UID)
+	}
+	switch {
+	case hasEnd:
+	case dur != 0:
+		event.End = event.Start.Add(dur)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+	return nil
+}
+
+// unfold reads the content lines of r, joining folded lines
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty splits a content line into its name, parameters and value
+func parseProperty(line string) (property, error) {
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("invalid content line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string, len(parts)-1),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// parseDateTime parses a DATE or DATE-TIME value, reporting whether it was a date
+func parseDateTime(prop property, loc *time.Location, zones *zones) (time.Time, bool, error) {
+	value := prop.value
+	if prop.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeLayout, value)
+		return t, false, err
+	}
+	t, err := time.Parse(localDateTimeLayout, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if loc, err = zones.location(tzid, t.Year()); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	return time.Date(year, month, day, hour, min, sec, 0, loc), false, nil
+}
+
+// parseDuration parses an RFC 5545 duration such as PT1H30M, P1D or -P1W
+func parseDuration(value string) (time.Duration, error) {
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign, value = -1, value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+	var total time.Duration
+	var inTime bool
+	number := ""
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == 'T':
+			inTime = true
+		case c >= '0' && c <= '9':
+			number += string(c)
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(number)
+			if !ok || err != nil || (c == 'M' || c == 'H' || c == 'S') != inTime {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			total += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * total, nil
+}
+
+// unescapeText reverses escapeText
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
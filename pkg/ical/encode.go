@@ -0,0 +1,278 @@
+// Package ical reads and writes calendars in the iCalendar format (RFC 5545)
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// ContentType is the media type of iCalendar data
+const ContentType = "text/calendar"
+
+// uidDomain qualifies generated UIDs so they are globally unique, as RFC 5545 recommends
+const uidDomain = "meeting-scheduler"
+
+// dateTimeLayout formats UTC date-times
+const dateTimeLayout = "20060102T150405Z"
+
+// localDateTimeLayout formats date-times in a time zone given by a TZID
+const localDateTimeLayout = "20060102T150405"
+
+// maxLineOctets is the longest a content line may be before it is folded
+const maxLineOctets = 75
+
+// UID returns the iCalendar UID of an event. Imported events keep the UID
+// they were imported with. Every participant's copy of a meeting shares the
+// meeting's UID, so calendar clients treat them as one meeting; other events
+// use their own ID.
+func UID(event domain.CalendarEvent) string {
+	if event.UID != "" {
+		return event.UID
+	}
+	if event.MeetingID != nil {
+		return *event.MeetingID + "@" + uidDomain
+	}
+	return event.ID + "@" + uidDomain
+}
+
+// IsGenerated reports whether uid was generated by UID for an event of this
+// service rather than imported from another calendar
+func IsGenerated(uid string) bool {
+	return strings.HasSuffix(uid, "@"+uidDomain)
+}
+
+// Marshal encodes the events as a VCALENDAR with one VEVENT per event.
+// Recurring events are written once, with their rule and exceptions, in the
+// time zone they repeat in along with a VTIMEZONE describing it. All other
+// times are written in UTC; none are floating.
+func Marshal(events []domain.CalendarEvent) []byte {
+	var w writer
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//meeting-scheduler//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+
+	written := make(map[string]bool)
+	for _, event := range events {
+		if loc := eventZone(event); loc != nil && !written[loc.String()] {
+			written[loc.String()] = true
+			writeTimezone(&w, loc.String(), loc, event.StartTime)
+		}
+	}
+	for _, event := range events {
+		writeEvent(&w, event)
+	}
+	w.line("END", "VCALENDAR")
+	return []byte(w.String())
+}
+
+func writeEvent(w *writer, event domain.CalendarEvent) {
+	stamp := event.UpdatedAt
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", UID(event))
+	w.line("DTSTAMP", formatTime(stamp))
+	loc := eventZone(event)
+	w.times("DTSTART", loc, event.StartTime)
+	w.times("DTEND", loc, event.EndTime)
+	w.line("SUMMARY", escapeText(event.Title))
+	if event.Recurrence != "" {
+		w.line("RRULE", event.Recurrence)
+		if len(event.Exceptions) > 0 {
+			w.times("EXDATE", loc, event.Exceptions...)
+		}
+	}
+	if !event.CreatedAt.IsZero() {
+		w.line("CREATED", formatTime(event.CreatedAt))
+	}
+	w.line("END", "VEVENT")
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+// eventZone returns the time zone to write an event's times in, or nil for
+// UTC. Recurring events are written in the IANA time zone they repeat in, so
+// that clients expand them on the same wall clock across daylight saving
+// changes. Series without one repeat in UTC, whatever location their start
+// was read in.
+func eventZone(event domain.CalendarEvent) *time.Location {
+	if event.Recurrence == "" {
+		return nil
+	}
+	if loc := event.Location(); loc != time.UTC {
+		return loc
+	}
+	return nil
+}
+
+// writeTimezone writes a VTIMEZONE with the offsets loc observes in the year
+// of ref
+func writeTimezone(w *writer, tzid string, loc *time.Location, ref time.Time) {
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", tzid)
+
+	year := ref.In(loc).Year()
+	observances := zoneObservances(loc, year)
+	if len(observances) == 0 {
+		name, offset := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+		w.line("BEGIN", "STANDARD")
+		w.line("DTSTART", "19700101T000000")
+		w.line("TZOFFSETFROM", formatOffset(offset))
+		w.line("TZOFFSETTO", formatOffset(offset))
+		w.line("TZNAME", name)
+		w.line("END", "STANDARD")
+	}
+	for _, o := range observances {
+		component := "STANDARD"
+		if o.daylight {
+			component = "DAYLIGHT"
+		}
+		w.line("BEGIN", component)
+		w.line("DTSTART", o.onset.Format(localDateTimeLayout))
+		w.line("RRULE", fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%s", o.onset.Month(), o.byDay))
+		w.line("TZOFFSETFROM", formatOffset(o.from))
+		w.line("TZOFFSETTO", formatOffset(o.to))
+		w.line("TZNAME", o.name)
+		w.line("END", component)
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+// observance is a change of a time zone's UTC offset, as described by the
+// STANDARD and DAYLIGHT components of a VTIMEZONE
+type observance struct {
+	daylight bool
+	name     string
+	// onset is when the change happens, in the local time in effect before it
+	onset time.Time
+	// byDay is the weekday of the month it happens on every year, e.g. -1SU
+	byDay    string
+	from, to int
+}
+
+// zoneObservances returns the offset changes of loc in the year. They are
+// assumed to repeat yearly on the same weekday of the month, as the rules of
+// current time zones do.
+func zoneObservances(loc *time.Location, year int) []observance {
+	var observances []observance
+	for _, at := range zoneTransitions(loc, year) {
+		_, from := at.Add(-time.Second).Zone()
+		name, to := at.Zone()
+		local := at.In(time.FixedZone("", from))
+		_, _, day := local.Date()
+		daysInMonth := time.Date(local.Year(), local.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		ordinal := strconv.Itoa((day-1)/7 + 1)
+		if day+7 > daysInMonth {
+			ordinal = "-1"
+		}
+		observances = append(observances, observance{
+			daylight: at.IsDST(),
+			name:     name,
+			onset:    local,
+			byDay:    ordinal + strings.ToUpper(local.Weekday().String()[:2]),
+			from:     from,
+			to:       to,
+		})
+	}
+	return observances
+}
+
+// zoneTransitions returns the instants in the year at which loc changes its
+// offset from UTC
+func zoneTransitions(loc *time.Location, year int) []time.Time {
+	var transitions []time.Time
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc)
+	for day := start; day.Before(end); day = day.Add(24 * time.Hour) {
+		_, before := day.Zone()
+		_, after := day.Add(24 * time.Hour).Zone()
+		if before == after {
+			continue
+		}
+		// Narrow the change down to the second
+		n := sort.Search(24*60*60, func(i int) bool {
+			_, offset := day.Add(time.Duration(i) * time.Second).Zone()
+			return offset != before
+		})
+		transitions = append(transitions, day.Add(time.Duration(n)*time.Second))
+	}
+	return transitions
+}
+
+// formatOffset formats a UTC offset in seconds as a UTC-OFFSET value such as +0200
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	value := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		value += fmt.Sprintf("%02d", offset%60)
+	}
+	return value
+}
+
+// escapeText escapes a TEXT value as described in RFC 5545 section 3.3.11
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writer builds content lines terminated by CRLF, folding lines longer than
+// 75 octets without splitting UTF-8 sequences
+type writer struct {
+	strings.Builder
+}
+
+// times writes a date-time property with one or more values, in UTC if loc
+// is nil and otherwise in loc with its TZID
+func (w *writer) times(name string, loc *time.Location, times ...time.Time) {
+	values := make([]string, 0, len(times))
+	for _, t := range times {
+		if loc == nil {
+			values = append(values, formatTime(t))
+		} else {
+			values = append(values, t.In(loc).Format(localDateTimeLayout))
+		}
+	}
+	if loc != nil {
+		name += ";TZID=" + loc.String()
+	}
+	w.line(name, strings.Join(values, ","))
+}
+
+func (w *writer) line(name, value string) {
+	line := name + ":" + value
+	// Continuation lines start with a space, which counts towards their length
+	for limit := maxLineOctets; len(line) > limit; limit = maxLineOctets - 1 {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
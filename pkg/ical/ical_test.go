@@ -0,0 +1,328 @@
+package ical
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+func TestMarshal(t *testing.T) {
+	start := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	meetingID := "meeting-1"
+	events := []domain.CalendarEvent{
+		{
+			ID:         "event-1",
+			Title:      "Standup; daily, short",
+			StartTime:  start,
+			EndTime:    start.Add(15 * time.Minute),
+			MeetingID:  &meetingID,
+			Recurrence: "FREQ=DAILY;COUNT=5",
+			Exceptions: []time.Time{start.AddDate(0, 0, 2)},
+			UpdatedAt:  start.Add(-time.Hour),
+		},
+		{
+			ID:        "event-2",
+			Title:     strings.Repeat("Ünïcode ", 20),
+			StartTime: start.Add(time.Hour),
+			EndTime:   start.Add(2 * time.Hour),
+		},
+	}
+
+	data := string(Marshal(events))
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:meeting-1@meeting-scheduler\r\n",
+		"UID:event-2@meeting-scheduler\r\n",
+		"DTSTART:20240902T100000Z\r\n",
+		"DTEND:20240902T101500Z\r\n",
+		"DTSTAMP:20240902T090000Z\r\n",
+		`SUMMARY:Standup\; daily\, short` + "\r\n",
+		"RRULE:FREQ=DAILY;COUNT=5\r\n",
+		"EXDATE:20240904T100000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(data, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, data)
+		}
+	}
+	if strings.Count(data, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Expected one VEVENT per event, got:\n%s", data)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("Expected lines of at most %d octets, got %d: %q", maxLineOctets, len(line), line)
+		}
+	}
+	unfolded := strings.ReplaceAll(data, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat("Ünïcode ", 20)) {
+		t.Errorf("Expected the folded summary to unfold to the original title")
+	}
+}
+
+func TestMarshalRecurringInTimeZone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	start := time.Date(2024, 10, 21, 9, 0, 0, 0, berlin)
+	fixed := time.FixedZone("", 3*60*60)
+	local := time.Date(2024, 10, 23, 9, 0, 0, 0, time.Local)
+	events := []domain.CalendarEvent{
+		{
+			// Read back from storage with a fixed offset
+			ID:         "weekly",
+			StartTime:  start.In(time.FixedZone("", 2*60*60)),
+			EndTime:    start.Add(time.Hour),
+			Recurrence: "FREQ=WEEKLY",
+			TimeZone:   "Europe/Berlin",
+			Exceptions: []time.Time{start.AddDate(0, 0, 14).UTC()},
+		},
+		{
+			ID:         "fixed",
+			StartTime:  time.Date(2024, 10, 22, 9, 0, 0, 0, fixed),
+			EndTime:    time.Date(2024, 10, 22, 10, 0, 0, 0, fixed),
+			Recurrence: "FREQ=DAILY",
+		},
+		{
+			ID:         "local",
+			StartTime:  local,
+			EndTime:    local.Add(time.Hour),
+			Recurrence: "FREQ=DAILY",
+		},
+		{
+			ID:        "single",
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+		},
+	}
+
+	data := string(Marshal(events))
+
+	for _, expected := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20240331T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20241027T030000\r\nRRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\n",
+		"DTSTART;TZID=Europe/Berlin:20241021T090000\r\n",
+		"DTEND;TZID=Europe/Berlin:20241021T100000\r\n",
+		"EXDATE;TZID=Europe/Berlin:20241104T090000\r\n",
+		// Series without a time zone repeat in UTC and are written in UTC,
+		// whatever location their start was read in
+		"DTSTART:20241022T060000Z\r\n",
+		"DTSTART:" + local.UTC().Format("20060102T150405") + "Z\r\n",
+		// Single events stay in UTC
+		"DTSTART:20241021T070000Z\r\n",
+	} {
+		if !strings.Contains(data, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, data)
+		}
+	}
+	if strings.Count(data, "BEGIN:VTIMEZONE") != 1 {
+		t.Errorf("Expected one VTIMEZONE, got:\n%s", data)
+	}
+	for _, floating := range []string{"DTSTART:20241022T090000\r\n", "DTSTART:20241023T090000\r\n"} {
+		if strings.Contains(data, floating) {
+			t.Errorf("Expected no floating time %q in:\n%s", floating, data)
+		}
+	}
+
+	parsed, err := Parse(strings.NewReader(data), time.UTC)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(parsed) != 4 || !parsed[0].Start.Equal(start) || parsed[0].Start.Location().String() != "Europe/Berlin" {
+		t.Errorf("Expected the series to read back in its time zone, got %+v", parsed)
+	}
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:weekly@example.com",
+		"DTSTART;TZID=Europe/Berlin:20240902T090000",
+		"DTEND;TZID=Europe/Berlin:20240902T093000",
+		"SUMMARY:Team sync\\, weekly",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		"EXDATE;TZID=Europe/Berlin:20240909T090000,20240916T090000",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"DTSTART:19700101T000000Z",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:holiday@example.com",
+		"DTSTART;VALUE=DATE:20240903",
+		"SUMMARY:Public",
+		"  holiday",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:call@example.com",
+		"DTSTART:20240904T140000Z",
+		"DURATION:PT1H30M",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:weekly@example.com",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20240923T090000",
+		"DTSTART:20240923T150000",
+		"DTEND:20240923T153000",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Parse(strings.NewReader(data), tokyo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+
+	weekly := events[0]
+	if !weekly.Start.Equal(time.Date(2024, 9, 2, 9, 0, 0, 0, berlin)) || weekly.End.Sub(weekly.Start) != 30*time.Minute {
+		t.Errorf("Expected the TZID to be honoured, got %v - %v", weekly.Start, weekly.End)
+	}
+	if weekly.Summary != "Team sync, weekly" || weekly.Recurrence != "FREQ=WEEKLY;BYDAY=MO" {
+		t.Errorf("Unexpected summary or rule: %q %q", weekly.Summary, weekly.Recurrence)
+	}
+	if len(weekly.Exceptions) != 2 || !weekly.Exceptions[1].Equal(time.Date(2024, 9, 16, 9, 0, 0, 0, berlin)) {
+		t.Errorf("Expected both exceptions, got %v", weekly.Exceptions)
+	}
+
+	holiday := events[1]
+	if !holiday.AllDay || !holiday.Start.Equal(time.Date(2024, 9, 3, 0, 0, 0, 0, tokyo)) || !holiday.End.Equal(holiday.Start.AddDate(0, 0, 1)) {
+		t.Errorf("Expected an all-day event in the given location, got %v - %v", holiday.Start, holiday.End)
+	}
+	if holiday.Summary != "Public holiday" {
+		t.Errorf("Expected the folded summary to be unfolded, got %q", holiday.Summary)
+	}
+
+	call := events[2]
+	if !call.Cancelled || call.End.Sub(call.Start) != 90*time.Minute {
+		t.Errorf("Expected a cancelled 90 minute call, got %+v", call)
+	}
+
+	override := events[3]
+	if override.RecurrenceID == nil || !override.RecurrenceID.Equal(time.Date(2024, 9, 23, 9, 0, 0, 0, berlin)) || !override.Transparent {
+		t.Errorf("Expected a transparent override of the fourth occurrence, got %+v", override)
+	}
+	if !override.Start.Equal(time.Date(2024, 9, 23, 15, 0, 0, 0, tokyo)) {
+		t.Errorf("Expected floating times in the given location, got %v", override.Start)
+	}
+
+	for name, invalid := range map[string]string{
+		"no calendar":   "BEGIN:VEVENT\r\nEND:VEVENT",
+		"no UID":        "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20240902T090000Z\r\nEND:VEVENT\r\nEND:VCALENDAR",
+		"bad duration":  "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nDTSTART:20240902T090000Z\r\nDURATION:1H\r\nEND:VEVENT\r\nEND:VCALENDAR",
+		"unterminated":  "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\n",
+		"missing colon": "BEGIN:VCALENDAR\r\nSUMMARY\r\nEND:VCALENDAR",
+	} {
+		if _, err := Parse(strings.NewReader(invalid), time.UTC); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseTimeZones(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		// Outlook describes its Windows time zones with rules since 1601
+		"BEGIN:VTIMEZONE",
+		"TZID:(UTC+01:00) Amsterdam, Berlin",
+		"BEGIN:STANDARD",
+		"DTSTART:16011028T030000",
+		"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:16010325T020000",
+		"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"END:DAYLIGHT",
+		"END:VTIMEZONE",
+		"BEGIN:VTIMEZONE",
+		"TZID:Custom India",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T000000",
+		"TZOFFSETFROM:+0530",
+		"TZOFFSETTO:+0530",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:windows@example.com",
+		"DTSTART;TZID=W. Europe Standard Time:20240902T090000",
+		"DTEND;TZID=W. Europe Standard Time:20240902T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:described@example.com",
+		`DTSTART;TZID="(UTC+01:00) Amsterdam, Berlin":20240701T090000`,
+		`DTEND;TZID="(UTC+01:00) Amsterdam, Berlin":20240701T100000`,
+		"RRULE:FREQ=WEEKLY",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:fixed@example.com",
+		"DTSTART;TZID=Custom India:20240902T090000",
+		"DTEND;TZID=Custom India:20240902T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:mars@example.com",
+		"DTSTART;TZID=Mars/Olympus:20240902T090000",
+		"DTEND;TZID=Mars/Olympus:20240902T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Parse(strings.NewReader(data), time.UTC)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+
+	if windows := events[0]; windows.Err != nil || !windows.Start.Equal(time.Date(2024, 9, 2, 9, 0, 0, 0, berlin)) {
+		t.Errorf("Expected the Windows time zone name to be resolved, got %v (%v)", windows.Start, windows.Err)
+	}
+
+	described := events[1]
+	if described.Err != nil || !described.Start.Equal(time.Date(2024, 7, 1, 9, 0, 0, 0, berlin)) {
+		t.Fatalf("Expected the VTIMEZONE to be resolved, got %v (%v)", described.Start, described.Err)
+	}
+	// The series follows the zone's daylight saving rules
+	winter := time.Date(2024, 12, 2, 9, 0, 0, 0, described.Start.Location())
+	if !winter.Equal(time.Date(2024, 12, 2, 9, 0, 0, 0, berlin)) {
+		t.Errorf("Expected %v to observe Berlin's daylight saving time", described.Start.Location())
+	}
+
+	if fixed := events[2]; fixed.Err != nil || !fixed.Start.Equal(time.Date(2024, 9, 2, 3, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected the fixed offset VTIMEZONE to be resolved, got %v (%v)", fixed.Start, fixed.Err)
+	}
+
+	if mars := events[3]; mars.UID != "mars@example.com" || !errors.Is(mars.Err, errUnknownTimeZone) {
+		t.Errorf("Expected only the event in an unknown time zone to fail, got %+v", mars)
+	}
+}
@@ -0,0 +1,348 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errUnknownTimeZone is reported for TZIDs that cannot be resolved to a time
+// zone; only the events using them are affected
+var errUnknownTimeZone = errors.New("unknown time zone")
+
+// windowsZones maps the Windows time zone names used by Outlook and Exchange
+// to IANA time zones, following the CLDR mapping for their main territory
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"UTC-11":                          "Etc/GMT+11",
+	"Aleutian Standard Time":          "America/Adak",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Marquesas Standard Time":         "Pacific/Marquesas",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"UTC-09":                          "Etc/GMT+9",
+	"Pacific Standard Time (Mexico)":  "America/Tijuana",
+	"UTC-08":                          "Etc/GMT+8",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time (Mexico)": "America/Mazatlan",
+	"Mountain Standard Time":          "America/Denver",
+	"Yukon Standard Time":             "America/Whitehorse",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Easter Island Standard Time":     "Pacific/Easter",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time (Mexico)":  "America/Cancun",
+	"Eastern Standard Time":           "America/New_York",
+	"Haiti Standard Time":             "America/Port-au-Prince",
+	"Cuba Standard Time":              "America/Havana",
+	"US Eastern Standard Time":        "America/Indiana/Indianapolis",
+	"Turks And Caicos Standard Time":  "America/Grand_Turk",
+	"Paraguay Standard Time":          "America/Asuncion",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Venezuela Standard Time":         "America/Caracas",
+	"Central Brazilian Standard Time": "America/Cuiaba",
+	"SA Western Standard Time":        "America/La_Paz",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"Tocantins Standard Time":         "America/Araguaina",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"SA Eastern Standard Time":        "America/Cayenne",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"Greenland Standard Time":         "America/Godthab",
+	"Montevideo Standard Time":        "America/Montevideo",
+	"Magallanes Standard Time":        "America/Punta_Arenas",
+	"Saint Pierre Standard Time":      "America/Miquelon",
+	"Bahia Standard Time":             "America/Bahia",
+	"UTC-02":                          "Etc/GMT+2",
+	"Azores Standard Time":            "Atlantic/Azores",
+	"Cape Verde Standard Time":        "Atlantic/Cape_Verde",
+	"UTC":                             "Etc/UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"Sao Tome Standard Time":          "Africa/Sao_Tome",
+	"Morocco Standard Time":           "Africa/Casablanca",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"Jordan Standard Time":            "Asia/Amman",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Middle East Standard Time":       "Asia/Beirut",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Syria Standard Time":             "Asia/Damascus",
+	"West Bank Standard Time":         "Asia/Hebron",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"FLE Standard Time":               "Europe/Kiev",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Sudan Standard Time":       "Africa/Juba",
+	"Kaliningrad Standard Time":       "Europe/Kaliningrad",
+	"Sudan Standard Time":             "Africa/Khartoum",
+	"Libya Standard Time":             "Africa/Tripoli",
+	"Namibia Standard Time":           "Africa/Windhoek",
+	"Arabic Standard Time":            "Asia/Baghdad",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Belarus Standard Time":           "Europe/Minsk",
+	"Russian Standard Time":           "Europe/Moscow",
+	"E. Africa Standard Time":         "Africa/Nairobi",
+	"Volgograd Standard Time":         "Europe/Volgograd",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Astrakhan Standard Time":         "Europe/Astrakhan",
+	"Azerbaijan Standard Time":        "Asia/Baku",
+	"Russia Time Zone 3":              "Europe/Samara",
+	"Mauritius Standard Time":         "Indian/Mauritius",
+	"Saratov Standard Time":           "Europe/Saratov",
+	"Georgian Standard Time":          "Asia/Tbilisi",
+	"Caucasus Standard Time":          "Asia/Yerevan",
+	"Afghanistan Standard Time":       "Asia/Kabul",
+	"West Asia Standard Time":         "Asia/Tashkent",
+	"Ekaterinburg Standard Time":      "Asia/Yekaterinburg",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"Qyzylorda Standard Time":         "Asia/Qyzylorda",
+	"India Standard Time":             "Asia/Kolkata",
+	"Sri Lanka Standard Time":         "Asia/Colombo",
+	"Nepal Standard Time":             "Asia/Kathmandu",
+	"Central Asia Standard Time":      "Asia/Almaty",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"Omsk Standard Time":              "Asia/Omsk",
+	"Myanmar Standard Time":           "Asia/Yangon",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"Altai Standard Time":             "Asia/Barnaul",
+	"W. Mongolia Standard Time":       "Asia/Hovd",
+	"North Asia Standard Time":        "Asia/Krasnoyarsk",
+	"N. Central Asia Standard Time":   "Asia/Novosibirsk",
+	"Tomsk Standard Time":             "Asia/Tomsk",
+	"China Standard Time":             "Asia/Shanghai",
+	"North Asia East Standard Time":   "Asia/Irkutsk",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"Ulaanbaatar Standard Time":       "Asia/Ulaanbaatar",
+	"Aus Central W. Standard Time":    "Australia/Eucla",
+	"Transbaikal Standard Time":       "Asia/Chita",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"North Korea Standard Time":       "Asia/Pyongyang",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Yakutsk Standard Time":           "Asia/Yakutsk",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"West Pacific Standard Time":      "Pacific/Port_Moresby",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"Vladivostok Standard Time":       "Asia/Vladivostok",
+	"Lord Howe Standard Time":         "Australia/Lord_Howe",
+	"Bougainville Standard Time":      "Pacific/Bougainville",
+	"Russia Time Zone 10":             "Asia/Srednekolymsk",
+	"Magadan Standard Time":           "Asia/Magadan",
+	"Norfolk Standard Time":           "Pacific/Norfolk",
+	"Sakhalin Standard Time":          "Asia/Sakhalin",
+	"Central Pacific Standard Time":   "Pacific/Guadalcanal",
+	"Russia Time Zone 11":             "Asia/Kamchatka",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"UTC+12":                          "Etc/GMT-12",
+	"Fiji Standard Time":              "Pacific/Fiji",
+	"Chatham Islands Standard Time":   "Pacific/Chatham",
+	"UTC+13":                          "Etc/GMT-13",
+	"Tonga Standard Time":             "Pacific/Tongatapu",
+	"Samoa Standard Time":             "Pacific/Apia",
+	"Line Islands Standard Time":      "Pacific/Kiritimati",
+}
+
+// vtimezone is what is needed of a VTIMEZONE to find the IANA time zone it
+// describes
+type vtimezone struct {
+	// location is the IANA name some clients give in X-LIC-LOCATION
+	location string
+	// rules are the keys of the observances that repeat every year without end
+	rules []string
+	// offset is the UTC offset of the latest observance, for zones without
+	// such rules
+	offset int
+	latest string
+}
+
+// zones resolves the TZIDs of a file to time zones
+type zones struct {
+	defined  map[string]*vtimezone
+	resolved map[string]*time.Location
+}
+
+// readTimezones collects the VTIMEZONEs of the content lines by TZID. Lines
+// that cannot be parsed are left for Parse to report.
+func readTimezones(lines []string) *zones {
+	z := &zones{defined: make(map[string]*vtimezone), resolved: make(map[string]*time.Location)}
+
+	var (
+		current  *vtimezone
+		tzid     string
+		inRule   bool
+		onset    string
+		rrule    string
+		from, to int
+	)
+	for _, line := range lines {
+		prop, err := parseProperty(line)
+		if err != nil {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTIMEZONE"):
+			current, tzid = &vtimezone{}, ""
+		case current == nil:
+		case prop.name == "END" && strings.EqualFold(prop.value, "VTIMEZONE"):
+			if tzid != "" {
+				z.defined[tzid] = current
+			}
+			current = nil
+		case prop.name == "BEGIN":
+			inRule, onset, rrule, from, to = true, "", "", 0, 0
+		case prop.name == "END" && inRule:
+			inRule = false
+			if key, ok := yearlyRule(rrule, onset, from, to); ok {
+				current.rules = append(current.rules, key)
+			} else if onset >= current.latest {
+				current.latest, current.offset = onset, to
+			}
+		case prop.name == "TZID":
+			tzid = prop.value
+		case prop.name == "X-LIC-LOCATION":
+			current.location = prop.value
+		case prop.name == "DTSTART" && inRule:
+			onset = prop.value
+		case prop.name == "RRULE" && inRule:
+			rrule = prop.value
+		case prop.name == "TZOFFSETFROM" && inRule:
+			from, _ = parseOffset(prop.value)
+		case prop.name == "TZOFFSETTO" && inRule:
+			to, _ = parseOffset(prop.value)
+		}
+	}
+	return z
+}
+
+// location resolves a TZID used in the given year: an IANA name, a Windows
+// name, or a VTIMEZONE of the file, which is matched against the IANA zones
+// of the Windows names by its offsets and daylight saving rules
+func (z *zones) location(tzid string, year int) (*time.Location, error) {
+	if loc, ok := z.resolved[tzid]; ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+	if err != nil {
+		loc = nil
+		if name, ok := windowsZones[tzid]; ok {
+			loc, _ = time.LoadLocation(name)
+		}
+	}
+	if defined := z.defined[tzid]; loc == nil && defined != nil {
+		loc = defined.match(year)
+	}
+	if loc == nil {
+		return nil, fmt.Errorf("%w %q", errUnknownTimeZone, tzid)
+	}
+	z.resolved[tzid] = loc
+	return loc, nil
+}
+
+// match returns the IANA time zone the VTIMEZONE describes, or nil
+func (v *vtimezone) match(year int) *time.Location {
+	if loc, err := time.LoadLocation(v.location); v.location != "" && err == nil {
+		return loc
+	}
+	if len(v.rules) == 0 && v.latest == "" {
+		return nil
+	}
+	want := fixedRule(v.offset)
+	if len(v.rules) > 0 {
+		sort.Strings(v.rules)
+		want = strings.Join(v.rules, "|")
+	}
+
+	candidates := make([]string, 0, len(windowsZones))
+	seen := make(map[string]bool, len(windowsZones))
+	for _, name := range windowsZones {
+		if !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	for _, name := range candidates {
+		loc, err := time.LoadLocation(name)
+		if err == nil && zoneRules(loc, year) == want {
+			return loc
+		}
+	}
+	return nil
+}
+
+// zoneRules describes the offsets and daylight saving rules loc observes in
+// the year in the form of the keys of a parsed VTIMEZONE
+func zoneRules(loc *time.Location, year int) string {
+	observances := zoneObservances(loc, year)
+	if len(observances) == 0 {
+		_, offset := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+		return fixedRule(offset)
+	}
+	keys := make([]string, 0, len(observances))
+	for _, o := range observances {
+		keys = append(keys, ruleKey(o.onset.Month(), o.byDay, o.onset.Format("150405"), o.from, o.to))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "|")
+}
+
+// yearlyRule returns the key of an observance that repeats every year on a
+// weekday of a month without end, as current daylight saving rules do
+func yearlyRule(rrule, onset string, from, to int) (string, bool) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(strings.ToUpper(rrule), ";") {
+		if name, value, ok := strings.Cut(part, "="); ok {
+			parts[name] = value
+		}
+	}
+	month, err := strconv.Atoi(parts["BYMONTH"])
+	if parts["FREQ"] != "YEARLY" || err != nil || parts["BYDAY"] == "" || parts["UNTIL"] != "" || parts["COUNT"] != "" || len(onset) != len(localDateTimeLayout) {
+		return "", false
+	}
+	byDay := strings.TrimPrefix(parts["BYDAY"], "+")
+	return ruleKey(time.Month(month), byDay, onset[9:], from, to), true
+}
+
+func ruleKey(month time.Month, byDay, clock string, from, to int) string {
+	return fmt.Sprintf("%d %s %s %d %d", month, byDay, clock, from, to)
+}
+
+func fixedRule(offset int) string {
+	return fmt.Sprintf("fixed %d", offset)
+}
+
+// parseOffset parses a UTC-OFFSET value such as +0200 or -033000 into seconds
+func parseOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 || value[0] != '+' && value[0] != '-' {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+	var parts [3]int
+	for i := 0; 1+2*i < len(value); i++ {
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", value)
+		}
+		parts[i] = n
+	}
+	offset := parts[0]*3600 + parts[1]*60 + parts[2]
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
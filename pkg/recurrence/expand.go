@@ -0,0 +1,66 @@
+package recurrence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// Expand returns the occurrences of a recurring event that overlap
+// [from, to), leaving out its exceptions. The series is expanded in the
+// event's time zone, whatever location its start time was read in. Each
+// occurrence carries the series' ID and its own start time as RecurrenceID.
+// Events without a recurrence rule are returned unchanged if they overlap the
+// window.
+func Expand(event domain.CalendarEvent, from, to time.Time) ([]domain.CalendarEvent, error) {
+	if event.Recurrence == "" {
+		if event.StartTime.Before(to) && event.EndTime.After(from) {
+			return []domain.CalendarEvent{event}, nil
+		}
+		return nil, nil
+	}
+
+	rule, err := Parse(event.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("event %s: %w", event.ID, err)
+	}
+
+	duration := event.EndTime.Sub(event.StartTime)
+	var occurrences []domain.CalendarEvent
+	dtstart := event.StartTime.In(event.Location())
+	for _, start := range rule.Between(dtstart, from.Add(-duration), to) {
+		if !start.Add(duration).After(from) || isException(event.Exceptions, start) {
+			continue
+		}
+		start := start
+		occurrence := event
+		occurrence.StartTime = start
+		occurrence.EndTime = start.Add(duration)
+		occurrence.RecurrenceID = &start
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// ExpandAll expands every event with Expand
+func ExpandAll(events []domain.CalendarEvent, from, to time.Time) ([]domain.CalendarEvent, error) {
+	expanded := make([]domain.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		occurrences, err := Expand(event, from, to)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, occurrences...)
+	}
+	return expanded, nil
+}
+
+func isException(exceptions []time.Time, start time.Time) bool {
+	for _, exception := range exceptions {
+		if exception.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
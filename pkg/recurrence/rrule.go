@@ -0,0 +1,409 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Horizon bounds how far ahead open-ended series are expanded, both when
+// checking a new series for conflicts and when reading calendars
+const Horizon = 365 * 24 * time.Hour
+
+// maxOccurrences guards against rules that would expand to an unreasonable
+// number of occurrences within the horizon
+const maxOccurrences = 5000
+
+// Frequencies supported in the FREQ part of a rule
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// Rule is a parsed RFC 5545 recurrence rule. The FREQ, INTERVAL, COUNT,
+// UNTIL, BYDAY, BYMONTHDAY and WKST parts are supported.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	// WeekStart is the day weeks start on, Monday unless WKST says
+	// otherwise. It only matters for weekly rules with an INTERVAL and BYDAY.
+	WeekStart time.Weekday
+}
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is the ordinal
+// within the month or year; zero means every such weekday.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Sunday:    "SU",
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+}
+
+// untilLayouts are the UNTIL formats allowed by RFC 5545: a UTC date-time,
+// a floating date-time and a date
+var untilLayouts = []string{"20060102T150405Z", "20060102T150405", "20060102"}
+
+// Parse parses a recurrence rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// A leading "RRULE:" is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("recurrence rule is empty")
+	}
+
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			var ok bool
+			if rule.WeekStart, ok = weekdays[strings.ToUpper(value)]; !ok {
+				err = fmt.Errorf("invalid weekday %q", value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %q", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in recurrence rule: %w", strings.ToUpper(name), err)
+		}
+	}
+
+	switch rule.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return nil, errors.New("recurrence rule must have a FREQ")
+	default:
+		return nil, fmt.Errorf("unsupported recurrence frequency %q", rule.Freq)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("recurrence rule cannot have both COUNT and UNTIL")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly {
+			return nil, errors.New("BYDAY ordinals are only supported with FREQ=MONTHLY")
+		}
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == Yearly {
+		return nil, errors.New("BYDAY is not supported with FREQ=YEARLY")
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date or date-time", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, entry := range strings.Split(strings.ToUpper(value), ",") {
+		if len(entry) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", entry)
+		}
+		weekday, ok := weekdays[entry[len(entry)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", entry)
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if ordinal := entry[:len(entry)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid weekday %q", entry)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, entry := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(entry)
+		if err != nil || day == 0 || day < -31 || day > 31 {
+			return nil, fmt.Errorf("invalid day of month %q", entry)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// String formats the rule in RFC 5545 syntax, without the "RRULE:" prefix
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayouts[0]))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			name := weekdayNames[day.Weekday]
+			if day.N != 0 {
+				name = strconv.Itoa(day.N) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the start times of the series that begins at dtstart,
+// up to but excluding before. dtstart is always the first occurrence. Times
+// keep dtstart's location and wall clock time, so a series stays at the same
+// local time across daylight saving changes.
+func (r *Rule) Occurrences(dtstart, before time.Time) []time.Time {
+	return r.Between(dtstart, dtstart, before)
+}
+
+// Between returns the start times of the series that begins at dtstart
+// within [from, before). Only the occurrences in that window count towards
+// maxOccurrences, so series that began long ago still expand; without a
+// COUNT, the periods before from are skipped rather than enumerated.
+func (r *Rule) Between(dtstart, from, before time.Time) []time.Time {
+	if !dtstart.Before(before) {
+		return nil
+	}
+	var occurrences []time.Time
+	if !dtstart.Before(from) {
+		occurrences = append(occurrences, dtstart)
+	}
+	// seen counts every occurrence since dtstart, as COUNT does
+	seen := 1
+	first := 0
+	if r.Count == 0 {
+		first = r.periodBefore(dtstart, from)
+	}
+	for period := first; len(occurrences) < maxOccurrences; period++ {
+		candidates, periodStart := r.period(dtstart, period)
+		if !periodStart.Before(before) {
+			break
+		}
+		for _, t := range candidates {
+			if !t.After(dtstart) {
+				continue
+			}
+			if !t.Before(before) || r.Count > 0 && seen >= r.Count || !r.Until.IsZero() && t.After(r.Until) {
+				return occurrences
+			}
+			seen++
+			if !t.Before(from) {
+				occurrences = append(occurrences, t)
+			}
+		}
+	}
+	return occurrences
+}
+
+// periodBefore returns the number of a period of the series that starts
+// before from, close enough to it to begin expanding there. It errs one
+// period early so that daylight saving changes cannot make it skip one.
+func (r *Rule) periodBefore(dtstart, from time.Time) int {
+	if !from.After(dtstart) {
+		return 0
+	}
+	from = from.In(dtstart.Location())
+	var elapsed int
+	switch r.Freq {
+	case Daily:
+		elapsed = int(from.Sub(dtstart).Hours() / 24)
+	case Weekly:
+		elapsed = int(from.Sub(dtstart).Hours() / (24 * 7))
+	case Monthly:
+		elapsed = (from.Year()-dtstart.Year())*12 + int(from.Month()-dtstart.Month())
+	case Yearly:
+		elapsed = from.Year() - dtstart.Year()
+	}
+	if n := elapsed/r.Interval - 1; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Last returns the start of the series' final occurrence, or false if the
+// series does not end within the horizon
+func (r *Rule) Last(dtstart time.Time) (time.Time, bool) {
+	horizon := dtstart.Add(Horizon)
+	if r.Count == 0 && (r.Until.IsZero() || !r.Until.Before(horizon)) {
+		return time.Time{}, false
+	}
+	occurrences := r.Occurrences(dtstart, horizon)
+	if r.Count > 0 && len(occurrences) < r.Count {
+		return time.Time{}, false
+	}
+	return occurrences[len(occurrences)-1], true
+}
+
+// period returns the candidate occurrences of the given period of the series,
+// in chronological order, along with the period's first possible start
+func (r *Rule) period(dtstart time.Time, n int) ([]time.Time, time.Time) {
+	hour, min, sec := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, dtstart.Nanosecond(), loc)
+	}
+	year, month, day := dtstart.Date()
+	step := n * r.Interval
+
+	var candidates []time.Time
+	var periodStart time.Time
+	switch r.Freq {
+	case Daily:
+		periodStart = at(year, month, day+step)
+		if r.matchesWeekday(periodStart.Weekday()) {
+			candidates = append(candidates, periodStart)
+		}
+	case Weekly:
+		weekStart := day - (int(dtstart.Weekday()-r.WeekStart)+7)%7 + 7*step
+		periodStart = at(year, month, weekStart)
+		if len(r.ByDay) == 0 {
+			candidates = append(candidates, at(year, month, day+7*step))
+		}
+		for offset := 0; offset < 7; offset++ {
+			if t := at(year, month, weekStart+offset); len(r.ByDay) > 0 && r.matchesWeekday(t.Weekday()) {
+				candidates = append(candidates, t)
+			}
+		}
+	case Monthly:
+		first := at(year, month+time.Month(step), 1)
+		periodStart = first
+		candidates = r.monthDays(first, day, at)
+	case Yearly:
+		periodStart = at(year+step, time.January, 1)
+		if t := at(year+step, month, day); t.Day() == day {
+			candidates = append(candidates, t)
+		}
+	}
+	return candidates, periodStart
+}
+
+// monthDays returns the candidates within the month starting at first
+func (r *Rule) monthDays(first time.Time, dtstartDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	year, month := first.Year(), first.Month()
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	var days []int
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = daysInMonth + d + 1
+			}
+			if d >= 1 && d <= daysInMonth {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for d := 1; d <= daysInMonth; d++ {
+			weekday := at(year, month, d).Weekday()
+			for _, byDay := range r.ByDay {
+				if byDay.Weekday != weekday {
+					continue
+				}
+				nth, nthFromEnd := (d-1)/7+1, -((daysInMonth-d)/7 + 1)
+				if byDay.N == 0 || byDay.N == nth || byDay.N == nthFromEnd {
+					days = append(days, d)
+					break
+				}
+			}
+		}
+	case dtstartDay <= daysInMonth:
+		days = append(days, dtstartDay)
+	}
+
+	sort.Ints(days)
+	candidates := make([]time.Time, 0, len(days))
+	for i, d := range days {
+		if i > 0 && days[i-1] == d {
+			continue
+		}
+		candidates = append(candidates, at(year, month, d))
+	}
+	return candidates
+}
+
+func (r *Rule) matchesWeekday(weekday time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
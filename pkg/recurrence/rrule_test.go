@@ -0,0 +1,341 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+		wantErr  bool
+	}{
+		{rule: "FREQ=WEEKLY;BYDAY=MO", expected: "FREQ=WEEKLY;BYDAY=MO"},
+		{rule: "RRULE:freq=weekly;interval=2;byday=tu,th", expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH"},
+		{rule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=6", expected: "FREQ=MONTHLY;COUNT=6;BYDAY=-1FR"},
+		{rule: "FREQ=DAILY;UNTIL=20240930", expected: "FREQ=DAILY;UNTIL=20240930T000000Z"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1;WKST=MO", expected: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=WEEKLY;COUNT=0", wantErr: true},
+		{rule: "FREQ=WEEKLY;COUNT=3;UNTIL=20240930", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=2MO", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYSETPOS=1", wantErr: true},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=su", expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU"},
+		{rule: "FREQ=WEEKLY;WKST=XX", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if rule.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rule.String())
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+	monday := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 10, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  time.Time
+		before   time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "Weekly on several days",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,WE",
+			dtstart:  monday,
+			before:   date(9, 12),
+			expected: []time.Time{date(9, 2), date(9, 4), date(9, 9), date(9, 11)},
+		},
+		{
+			name:     "Biweekly",
+			rule:     "FREQ=WEEKLY;INTERVAL=2",
+			dtstart:  monday,
+			before:   date(10, 1),
+			expected: []time.Time{date(9, 2), date(9, 16), date(9, 30)},
+		},
+		{
+			name:     "Biweekly with weeks starting on Monday",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart:  date(9, 3),
+			before:   date(12, 31),
+			expected: []time.Time{date(9, 3), date(9, 8), date(9, 17), date(9, 22)},
+		},
+		{
+			name:     "Biweekly with weeks starting on Sunday",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart:  date(9, 3),
+			before:   date(12, 31),
+			expected: []time.Time{date(9, 3), date(9, 15), date(9, 17), date(9, 29)},
+		},
+		{
+			name:     "Count includes the first occurrence",
+			rule:     "FREQ=DAILY;COUNT=3",
+			dtstart:  monday,
+			before:   date(12, 31),
+			expected: []time.Time{date(9, 2), date(9, 3), date(9, 4)},
+		},
+		{
+			name:     "Until is inclusive",
+			rule:     "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20240909T100000Z",
+			dtstart:  date(9, 5),
+			before:   date(12, 31),
+			expected: []time.Time{date(9, 5), date(9, 6), date(9, 9)},
+		},
+		{
+			name:     "Last Friday of the month",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart:  date(9, 27),
+			before:   date(12, 31),
+			expected: []time.Time{date(9, 27), date(10, 25), date(11, 29)},
+		},
+		{
+			name:     "Monthly skips months without the day",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			dtstart:  date(8, 31),
+			before:   date(12, 31).AddDate(0, 0, 1),
+			expected: []time.Time{date(8, 31), date(10, 31), date(12, 31)},
+		},
+		{
+			name:    "Local time is kept across daylight saving changes",
+			rule:    "FREQ=WEEKLY;COUNT=2",
+			dtstart: time.Date(2024, 10, 21, 9, 0, 0, 0, berlin),
+			before:  time.Date(2024, 12, 31, 0, 0, 0, 0, berlin),
+			expected: []time.Time{
+				time.Date(2024, 10, 21, 7, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 28, 8, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			occurrences := rule.Occurrences(tt.dtstart, tt.before)
+			if len(occurrences) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, occurrences)
+			}
+			for i := range occurrences {
+				if !occurrences[i].Equal(tt.expected[i]) {
+					t.Errorf("Expected occurrence %d at %v, got %v", i, tt.expected[i], occurrences[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+	// Series that began long before the window; expanding them from dtstart
+	// would take more than maxOccurrences occurrences to reach it
+	dtstart := time.Date(2012, 1, 2, 9, 0, 0, 0, berlin)
+	from := time.Date(2026, 3, 23, 0, 0, 0, 0, berlin)
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 9, 0, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		before   time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "Daily",
+			rule:     "FREQ=DAILY",
+			before:   at(3, 26),
+			expected: []time.Time{at(3, 23), at(3, 24), at(3, 25)},
+		},
+		{
+			name:     "Daily across a daylight saving change",
+			rule:     "FREQ=DAILY;INTERVAL=2",
+			before:   at(4, 1),
+			expected: []time.Time{at(3, 23), at(3, 25), at(3, 27), at(3, 29), at(3, 31)},
+		},
+		{
+			name:     "Weekly on several days",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,FR",
+			before:   at(4, 1),
+			expected: []time.Time{at(3, 23), at(3, 27), at(3, 30)},
+		},
+		{
+			name:     "Monthly",
+			rule:     "FREQ=MONTHLY",
+			before:   at(6, 3),
+			expected: []time.Time{at(4, 2), at(5, 2), at(6, 2)},
+		},
+		{
+			name:     "Until before the window",
+			rule:     "FREQ=DAILY;UNTIL=20200101T000000Z",
+			before:   at(4, 1),
+			expected: nil,
+		},
+		{
+			name:     "Count reached before the window",
+			rule:     "FREQ=DAILY;COUNT=10",
+			before:   at(4, 1),
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			occurrences := rule.Between(dtstart, from, tt.before)
+			if len(occurrences) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, occurrences)
+			}
+			for i := range occurrences {
+				if !occurrences[i].Equal(tt.expected[i]) {
+					t.Errorf("Expected occurrence %d at %v, got %v", i, tt.expected[i], occurrences[i])
+				}
+			}
+		})
+	}
+
+	// Between agrees with Occurrences on series short enough to enumerate
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, berlin)
+	for _, text := range []string{"FREQ=DAILY;INTERVAL=3", "FREQ=WEEKLY;BYDAY=TU,SU", "FREQ=MONTHLY;BYDAY=-1FR", "FREQ=YEARLY"} {
+		rule, _ := Parse(text)
+		windowStart, windowEnd := start.AddDate(1, 2, 5), start.AddDate(2, 0, 0)
+		var expected []time.Time
+		for _, occurrence := range rule.Occurrences(start, windowEnd) {
+			if !occurrence.Before(windowStart) {
+				expected = append(expected, occurrence)
+			}
+		}
+		got := rule.Between(start, windowStart, windowEnd)
+		if len(got) != len(expected) {
+			t.Errorf("%s: expected %v, got %v", text, expected, got)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(expected[i]) {
+				t.Errorf("%s: expected occurrence %d at %v, got %v", text, i, expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestLast(t *testing.T) {
+	start := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+
+	rule, _ := Parse("FREQ=WEEKLY;COUNT=4")
+	if last, ok := rule.Last(start); !ok || !last.Equal(start.AddDate(0, 0, 21)) {
+		t.Errorf("Expected the 4th weekly occurrence, got %v (%v)", last, ok)
+	}
+
+	rule, _ = Parse("FREQ=WEEKLY")
+	if _, ok := rule.Last(start); ok {
+		t.Error("Expected an open-ended series not to have a last occurrence")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	start := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	event := domain.CalendarEvent{
+		ID:         "standup",
+		StartTime:  start,
+		EndTime:    start.Add(15 * time.Minute),
+		Recurrence: "FREQ=DAILY",
+		Exceptions: []time.Time{start.AddDate(0, 0, 2)},
+	}
+
+	occurrences, err := Expand(event, start.AddDate(0, 0, 1), start.AddDate(0, 0, 4))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []time.Time{start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)}
+	if len(occurrences) != len(expected) {
+		t.Fatalf("Expected %d occurrences, got %v", len(expected), occurrences)
+	}
+	for i, occurrence := range occurrences {
+		if !occurrence.StartTime.Equal(expected[i]) || !occurrence.EndTime.Equal(expected[i].Add(15*time.Minute)) {
+			t.Errorf("Expected occurrence at %v, got %v-%v", expected[i], occurrence.StartTime, occurrence.EndTime)
+		}
+		if occurrence.ID != "standup" || occurrence.RecurrenceID == nil || !occurrence.RecurrenceID.Equal(expected[i]) {
+			t.Errorf("Expected the occurrence to reference the series, got %+v", occurrence)
+		}
+	}
+
+	// A series started years ago still blocks a window today, including the
+	// occurrence that began before the window and runs into it
+	old := domain.CalendarEvent{
+		StartTime:  time.Date(2012, 1, 2, 23, 30, 0, 0, time.UTC),
+		EndTime:    time.Date(2012, 1, 3, 0, 30, 0, 0, time.UTC),
+		Recurrence: "FREQ=DAILY",
+	}
+	windowStart := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	occurrences, err = Expand(old, windowStart, windowStart.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(occurrences) != 2 || !occurrences[0].StartTime.Equal(windowStart.Add(-30*time.Minute)) {
+		t.Errorf("Expected the occurrences running into and within the window, got %v", occurrences)
+	}
+
+	// The series repeats in its own time zone, whatever location its start
+	// was read in: 9 AM in New York stays 9 AM after the switch to summer time
+	if _, err := time.LoadLocation("America/New_York"); err == nil {
+		winter := time.Date(2024, 3, 4, 14, 0, 0, 0, time.FixedZone("", 0))
+		zoned := domain.CalendarEvent{
+			StartTime:  winter,
+			EndTime:    winter.Add(time.Hour),
+			Recurrence: "FREQ=WEEKLY",
+			TimeZone:   "America/New_York",
+		}
+		summer := time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC)
+		occurrences, err = Expand(zoned, summer, summer.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(occurrences) != 1 || !occurrences[0].StartTime.Equal(summer) {
+			t.Errorf("Expected the occurrence at %v, got %v", summer, occurrences)
+		}
+	}
+
+	single := domain.CalendarEvent{StartTime: start, EndTime: start.Add(time.Hour)}
+	if occurrences, _ := Expand(single, start.Add(2*time.Hour), start.Add(3*time.Hour)); len(occurrences) != 0 {
+		t.Errorf("Expected a single event outside the window to be dropped, got %v", occurrences)
+	}
+
+	event.Recurrence = "FREQ=SOMETIMES"
+	if _, err := Expand(event, start, start.AddDate(0, 0, 1)); err == nil {
+		t.Error("Expected an error for an invalid stored rule")
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+	"github.com/meeting-scheduler/pkg/recurrence"
+)
+
+// maxPadding is the furthest an event's travel time and a participant's
+// buffers can reach beyond it, so calendars are read that much wider
+const maxPadding = time.Duration(domain.MaxBufferMinutes+domain.MaxTravelMinutes) * time.Minute
+
+// meetingOccurrences returns the occurrences of a meeting within the
+// recurrence horizon; a single meeting has exactly one
+func meetingOccurrences(meeting *domain.Meeting) ([]domain.CalendarEvent, error) {
+	return recurrence.Expand(domain.CalendarEvent{
+		StartTime:  meeting.StartTime,
+		EndTime:    meeting.EndTime,
+		Recurrence: meeting.Recurrence,
+		TimeZone:   meeting.TimeZone,
+		Exceptions: meeting.Exceptions,
+	}, meeting.StartTime, meeting.StartTime.Add(recurrence.Horizon))
+}
+
+// padding returns how far an existing event rules out the meeting before
+// its start and after its end: the event's travel time plus, for a
+// participant, the longest of the meeting's, the participant's and the
+// event's own buffers, as the scheduler applies them
+func padding(meeting *domain.Meeting, users map[string]domain.User) func(domain.CalendarEvent) (time.Duration, time.Duration) {
+	return func(event domain.CalendarEvent) (time.Duration, time.Duration) {
+		travel := minutes(event.TravelMinutes)
+		if event.ResourceID != nil {
+			return travel, travel
+		}
+		user := users[event.UserID]
+		before := minutes(max(meeting.BufferBeforeMinutes, user.BufferBeforeMinutes, event.BufferAfterMinutes))
+		after := minutes(max(meeting.BufferAfterMinutes, user.BufferAfterMinutes, event.BufferBeforeMinutes))
+		// The meeting has to end its after-buffer before the event starts,
+		// and start its before-buffer after the event ends
+		return travel + after, travel + before
+	}
+}
+
+// checkOccurrences returns ErrConflict if any of the events, recurring ones
+// expanded and each widened by pad, overlaps one of the occurrences
+func checkOccurrences(occurrences, events []domain.CalendarEvent, pad func(domain.CalendarEvent) (time.Duration, time.Duration)) error {
+	if len(occurrences) == 0 {
+		return nil
+	}
+	start, end := occurrences[0].StartTime, occurrences[len(occurrences)-1].EndTime
+	existing, err := recurrence.ExpandAll(events, start.Add(-maxPadding), end.Add(maxPadding))
+	if err != nil {
+		return err
+	}
+	for _, occurrence := range occurrences {
+		for _, event := range existing {
+			lead, trail := pad(event)
+			if event.StartTime.Add(-lead).Before(occurrence.EndTime) && occurrence.StartTime.Before(event.EndTime.Add(trail)) {
+				return ErrConflict
+			}
+		}
+	}
+	return nil
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
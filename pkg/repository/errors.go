@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ErrConflict is returned when a booking would overlap an event that was
+// written after the caller last read the participants' calendars
+var ErrConflict = errors.New("conflicting event already booked")
+
+// ErrNotFound is returned when the requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// MySQL error numbers of transactions aborted by lock contention, which are
+// reported as ErrConflict
+const (
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+// ErrUnavailable is returned when the database could not be reached or did
+// not answer in time; the call may succeed when retried
+var ErrUnavailable = errors.New("database unavailable")
+
+// translate reports gorm's not-found error as ErrNotFound and deadlocks and
+// lock wait timeouts as ErrConflict, marks connection failures and timeouts
+// with ErrUnavailable and prefixes every failure with the operation, keeping
+// the cause available to errors.Is
+func translate(op string, err error) error {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%s: %w", op, ErrNotFound)
+	case errors.As(err, &mysqlErr) && (mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout):
+		return fmt.Errorf("%s: %w: %w", op, ErrConflict, err)
+	case unreachable(err):
+		return fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+	default:
+		return fmt.Errorf("%s: %w", op, err)
+	}
+}
+
+// unreachable reports whether err means the connection to the database
+// failed or timed out
+func unreachable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestTranslate(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	lockWait := &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+
+	tests := []struct {
+		name        string
+		err         error
+		target      error
+		conflict    bool
+		unavailable bool
+	}{
+		{name: "Not found", err: gorm.ErrRecordNotFound, target: ErrNotFound},
+		{name: "Deadlock", err: deadlock, target: deadlock, conflict: true},
+		{name: "Lock wait timeout", err: lockWait, target: lockWait, conflict: true},
+		{name: "Other MySQL error", err: duplicate, target: duplicate},
+		{name: "Bad connection", err: driver.ErrBadConn, target: driver.ErrBadConn, unavailable: true},
+		{name: "Dial failure", err: refused, target: refused, unavailable: true},
+		{name: "Timeout", err: context.DeadlineExceeded, target: context.DeadlineExceeded, unavailable: true},
+		{name: "Other failure", err: gorm.ErrInvalidField, target: gorm.ErrInvalidField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translate("get user", tt.err)
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Expected %v to match %v", err, tt.target)
+			}
+			if errors.Is(err, ErrConflict) != tt.conflict {
+				t.Errorf("Expected %v to match ErrConflict: %t", err, tt.conflict)
+			}
+			if errors.Is(err, ErrUnavailable) != tt.unavailable {
+				t.Errorf("Expected %v to match ErrUnavailable: %t", err, tt.unavailable)
+			}
+		})
+	}
+}
@@ -0,0 +1,739 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+// MemoryRepository keeps all data in memory, for local development and
+// tests. It is safe for concurrent use, and every calendar is indexed by start
+// time so that looking up a window does not scan the whole calendar. Data is
+// lost when the process exits.
+type MemoryRepository struct {
+	mu        sync.RWMutex
+	users     map[string]domain.User
+	resources map[string]domain.Resource
+	meetings  map[string]domain.Meeting
+	events    map[string]domain.CalendarEvent
+	// calendars indexes the events of every user and resource
+	calendars map[owner]*calendar
+	// meetingEvents lists the IDs of every meeting's events in booking order
+	meetingEvents map[string][]string
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:         make(map[string]domain.User),
+		resources:     make(map[string]domain.Resource),
+		meetings:      make(map[string]domain.Meeting),
+		events:        make(map[string]domain.CalendarEvent),
+		calendars:     make(map[owner]*calendar),
+		meetingEvents: make(map[string][]string),
+	}
+}
+
+// owner identifies the user or resource a calendar belongs to
+type owner struct {
+	resource bool
+	id       string
+}
+
+func ownerOf(event domain.CalendarEvent) owner {
+	if event.ResourceID != nil {
+		return owner{resource: true, id: *event.ResourceID}
+	}
+	return owner{id: event.UserID}
+}
+
+// entry is a single event in a calendar's index
+type entry struct {
+	start time.Time
+	id    string
+}
+
+// calendar indexes one owner's events. Single events are sorted by start
+// time; since none is longer than longest, the events overlapping a window
+// start less than longest before it. Recurring events are checked one by one.
+type calendar struct {
+	single    []entry
+	recurring map[string]bool
+	longest   time.Duration
+}
+
+func newCalendar() *calendar {
+	return &calendar{recurring: make(map[string]bool)}
+}
+
+// position returns the index of the first entry at or after start and id
+func (c *calendar) position(start time.Time, id string) int {
+	return sort.Search(len(c.single), func(i int) bool {
+		e := c.single[i]
+		return e.start.After(start) || e.start.Equal(start) && e.id >= id
+	})
+}
+
+func (c *calendar) insert(event domain.CalendarEvent) {
+	if event.Recurrence != "" {
+		c.recurring[event.ID] = true
+		return
+	}
+	i := c.position(event.StartTime, event.ID)
+	c.single = append(c.single, entry{})
+	copy(c.single[i+1:], c.single[i:])
+	c.single[i] = entry{start: event.StartTime, id: event.ID}
+	if length := event.EndTime.Sub(event.StartTime); length > c.longest {
+		c.longest = length
+	}
+}
+
+func (c *calendar) remove(event domain.CalendarEvent) {
+	if event.Recurrence != "" {
+		delete(c.recurring, event.ID)
+		return
+	}
+	i := c.position(event.StartTime, event.ID)
+	if i < len(c.single) && c.single[i].id == event.ID {
+		c.single = append(c.single[:i], c.single[i+1:]...)
+	}
+}
+
+// ids returns the IDs of all events in the calendar
+func (c *calendar) ids() []string {
+	ids := make([]string, 0, len(c.single)+len(c.recurring))
+	for _, e := range c.single {
+		ids = append(ids, e.id)
+	}
+	for id := range c.recurring {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// overlapping returns the IDs of the events that overlap [start, end),
+// including recurring events whose series runs into it, like the
+// overlappingEvents condition of the MySQL repository
+func (c *calendar) overlapping(events map[string]domain.CalendarEvent, start, end time.Time) []string {
+	var ids []string
+	earliest := start.Add(-c.longest)
+	i := sort.Search(len(c.single), func(i int) bool { return c.single[i].start.After(earliest) })
+	for ; i < len(c.single) && c.single[i].start.Before(end); i++ {
+		if events[c.single[i].id].EndTime.After(start) {
+			ids = append(ids, c.single[i].id)
+		}
+	}
+	for id := range c.recurring {
+		event := events[id]
+		if event.StartTime.Before(end) &&
+			(event.EndTime.After(start) || event.RecurrenceEnd == nil || event.RecurrenceEnd.After(start)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// GetUser retrieves a user by ID
+func (r *MemoryRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("get user: %w", ErrNotFound)
+	}
+	return &user, nil
+}
+
+// GetUsers retrieves the users with the given IDs. Users that do not exist
+// are left out.
+func (r *MemoryRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]domain.User, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// ListUsers retrieves all users ordered by name
+func (r *MemoryRepository) ListUsers(ctx context.Context) ([]domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Name != users[j].Name {
+			return users[i].Name < users[j].Name
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+// CreateUser creates a new user
+func (r *MemoryRepository) CreateUser(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("create user: user %s already exists", user.ID)
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+// UpdateUser saves changes to an existing user
+func (r *MemoryRepository) UpdateUser(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = *user
+	return nil
+}
+
+// DeleteUser removes a user and their events. Meetings the user attended lose
+// them as a participant; meetings left without any participant are deleted
+// and meetings they organized are handed to the remaining participant with the
+// lowest ID.
+func (r *MemoryRepository) DeleteUser(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attended := make(map[string]bool)
+	if c, ok := r.calendars[owner{id: id}]; ok {
+		for _, eventID := range c.ids() {
+			event := r.events[eventID]
+			if event.MeetingID != nil {
+				attended[*event.MeetingID] = true
+			}
+			r.removeEvent(event)
+		}
+		delete(r.calendars, owner{id: id})
+	}
+
+	for meetingID, meeting := range r.meetings {
+		if !attended[meetingID] && meeting.OrganizerID != id {
+			continue
+		}
+		participants := r.participants(meetingID)
+		if len(participants) == 0 && attended[meetingID] {
+			// Release the resources booked for a meeting nobody attends anymore
+			r.deleteMeeting(meetingID)
+			continue
+		}
+		if meeting.OrganizerID == id {
+			meeting.OrganizerID = ""
+			if len(participants) > 0 {
+				sort.Strings(participants)
+				meeting.OrganizerID = participants[0]
+			}
+			r.meetings[meetingID] = meeting
+		}
+	}
+
+	delete(r.users, id)
+	return nil
+}
+
+// GetUserEvents retrieves a user's calendar events that overlap a time range,
+// including events that start before or end after it. Recurring events are
+// returned unexpanded if the series runs into the time range.
+func (r *MemoryRepository) GetUserEvents(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.calendarEvents(owner{id: userID}, start, end), nil
+}
+
+// GetEventsForUsers retrieves the events of several users that overlap a
+// time range, keyed by user ID, with the same semantics as GetUserEvents
+func (r *MemoryRepository) GetEventsForUsers(ctx context.Context, userIDs []string, start, end time.Time) (map[string][]domain.CalendarEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byUser := make(map[string][]domain.CalendarEvent, len(userIDs))
+	for _, userID := range userIDs {
+		if events := r.calendarEvents(owner{id: userID}, start, end); len(events) > 0 {
+			byUser[userID] = events
+		}
+	}
+	return byUser, nil
+}
+
+// GetEventsForResources retrieves the events booking several resources that
+// overlap a time range, keyed by resource ID, like GetEventsForUsers does for users
+func (r *MemoryRepository) GetEventsForResources(ctx context.Context, resourceIDs []string, start, end time.Time) (map[string][]domain.CalendarEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byResource := make(map[string][]domain.CalendarEvent, len(resourceIDs))
+	for _, resourceID := range resourceIDs {
+		if events := r.calendarEvents(owner{resource: true, id: resourceID}, start, end); len(events) > 0 {
+			byResource[resourceID] = events
+		}
+	}
+	return byResource, nil
+}
+
+// CreateEvent creates a new calendar event
+func (r *MemoryRepository) CreateEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.events[event.ID]; exists {
+		return fmt.Errorf("create event: event %s already exists", event.ID)
+	}
+	r.addEvent(cloneEvent(*event))
+	return nil
+}
+
+// GetEvent retrieves a calendar event by ID
+func (r *MemoryRepository) GetEvent(ctx context.Context, id string) (*domain.CalendarEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return nil, fmt.Errorf("get event: %w", ErrNotFound)
+	}
+	event = cloneEvent(event)
+	return &event, nil
+}
+
+// UpdateEvent saves changes to an existing calendar event
+func (r *MemoryRepository) UpdateEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveEvent(cloneEvent(*event))
+	return nil
+}
+
+// DeleteEvent removes a calendar event
+func (r *MemoryRepository) DeleteEvent(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event, ok := r.events[id]; ok {
+		r.removeEvent(event)
+	}
+	return nil
+}
+
+// ImportEvents stores events imported into the user's calendar. Events whose
+// UID the user already has replace the stored event, keeping its ID and
+// creation time; the others are created. The user's events with one of the
+// removed UIDs are deleted. It returns how many events were created and
+// deleted.
+func (r *MemoryRepository) ImportEvents(ctx context.Context, userID string, events []*domain.CalendarEvent, removedUIDs []string) (int, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byUID := make(map[string]domain.CalendarEvent)
+	if c, ok := r.calendars[owner{id: userID}]; ok {
+		for _, id := range c.ids() {
+			byUID[r.events[id].UID] = r.events[id]
+		}
+	}
+	// Check every event before storing any, so that the import stays atomic
+	for _, event := range events {
+		if _, ok := byUID[event.UID]; ok {
+			continue
+		}
+		if _, exists := r.events[event.ID]; exists {
+			return 0, 0, fmt.Errorf("import events: event %s already exists", event.ID)
+		}
+	}
+
+	removed := 0
+	for _, uid := range removedUIDs {
+		if stored, ok := byUID[uid]; ok {
+			r.removeEvent(stored)
+			delete(byUID, uid)
+			removed++
+		}
+	}
+
+	created := 0
+	for _, event := range events {
+		if stored, ok := byUID[event.UID]; ok {
+			event.ID = stored.ID
+			event.CreatedAt = stored.CreatedAt
+		} else {
+			created++
+		}
+		r.saveEvent(cloneEvent(*event))
+	}
+	return created, removed, nil
+}
+
+// CreateMeetingWithEvents creates a meeting together with its participants'
+// and resources' events, so either all of them are stored or none are.
+// ErrConflict is returned if one of the calendars has an event overlapping
+// one of the meeting's occurrences.
+func (r *MemoryRepository) CreateMeetingWithEvents(ctx context.Context, meeting *domain.Meeting, events []*domain.CalendarEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.meetings[meeting.ID]; exists {
+		return fmt.Errorf("create meeting: meeting %s already exists", meeting.ID)
+	}
+	owners := make([]owner, 0, len(events))
+	for _, event := range events {
+		if _, exists := r.events[event.ID]; exists {
+			return fmt.Errorf("create meeting: event %s already exists", event.ID)
+		}
+		owners = append(owners, ownerOf(*event))
+	}
+	if err := r.checkAvailability(owners, meeting); err != nil {
+		return fmt.Errorf("create meeting: %w", err)
+	}
+
+	r.meetings[meeting.ID] = cloneMeeting(*meeting)
+	for _, event := range events {
+		r.addEvent(cloneEvent(*event))
+	}
+	return nil
+}
+
+// GetMeeting retrieves a meeting by ID along with its participants and resources
+func (r *MemoryRepository) GetMeeting(ctx context.Context, id string) (*domain.Meeting, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	meeting, ok := r.meetings[id]
+	if !ok {
+		return nil, fmt.Errorf("get meeting: %w", ErrNotFound)
+	}
+	meeting = cloneMeeting(meeting)
+	meeting.ParticipantIDs = make([]string, 0, len(r.meetingEvents[id]))
+	for _, eventID := range r.meetingEvents[id] {
+		event := cloneEvent(r.events[eventID])
+		meeting.Events = append(meeting.Events, event)
+		if event.ResourceID != nil {
+			meeting.ResourceIDs = append(meeting.ResourceIDs, *event.ResourceID)
+			continue
+		}
+		meeting.ParticipantIDs = append(meeting.ParticipantIDs, event.UserID)
+	}
+	return &meeting, nil
+}
+
+// DeleteMeeting removes a meeting and all of its participants' events
+func (r *MemoryRepository) DeleteMeeting(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deleteMeeting(id)
+	return nil
+}
+
+// UpdateMeetingTime moves a meeting and all of its participants' and
+// resources' events to the meeting's new start and end time, along with the
+// exceptions and end of a recurring series. Like CreateMeetingWithEvents it
+// returns ErrConflict if one of the calendars is busy in the new slot.
+func (r *MemoryRepository) UpdateMeetingTime(ctx context.Context, meeting *domain.Meeting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.meetings[meeting.ID]
+	if !ok {
+		return fmt.Errorf("update meeting time: %w", ErrNotFound)
+	}
+	owners := make([]owner, 0, len(r.meetingEvents[meeting.ID]))
+	for _, eventID := range r.meetingEvents[meeting.ID] {
+		owners = append(owners, ownerOf(r.events[eventID]))
+	}
+	if err := r.checkAvailability(owners, meeting); err != nil {
+		return fmt.Errorf("update meeting time: %w", err)
+	}
+
+	stored.StartTime = meeting.StartTime
+	stored.EndTime = meeting.EndTime
+	stored.Exceptions = cloneTimes(meeting.Exceptions)
+	stored.RecurrenceEnd = cloneTime(meeting.RecurrenceEnd)
+	stored.BufferBeforeMinutes = meeting.BufferBeforeMinutes
+	stored.BufferAfterMinutes = meeting.BufferAfterMinutes
+	r.meetings[meeting.ID] = stored
+	for _, eventID := range r.meetingEvents[meeting.ID] {
+		event := r.events[eventID]
+		event.StartTime = meeting.StartTime
+		event.EndTime = meeting.EndTime
+		event.Exceptions = cloneTimes(meeting.Exceptions)
+		event.RecurrenceEnd = cloneTime(meeting.RecurrenceEnd)
+		if event.ResourceID == nil {
+			event.BufferBeforeMinutes = meeting.BufferBeforeMinutes
+			event.BufferAfterMinutes = meeting.BufferAfterMinutes
+		}
+		r.saveEvent(event)
+	}
+	return nil
+}
+
+// SetMeetingExceptions replaces the exceptions of a recurring meeting and of
+// all of its participants' events
+func (r *MemoryRepository) SetMeetingExceptions(ctx context.Context, id string, exceptions []time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	meeting, ok := r.meetings[id]
+	if !ok {
+		return fmt.Errorf("set meeting exceptions: %w", ErrNotFound)
+	}
+	meeting.Exceptions = cloneTimes(exceptions)
+	r.meetings[id] = meeting
+	for _, eventID := range r.meetingEvents[id] {
+		event := r.events[eventID]
+		event.Exceptions = cloneTimes(exceptions)
+		r.events[eventID] = event
+	}
+	return nil
+}
+
+// CreateResource creates a new resource
+func (r *MemoryRepository) CreateResource(ctx context.Context, resource *domain.Resource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.resources[resource.ID]; exists {
+		return fmt.Errorf("create resource: resource %s already exists", resource.ID)
+	}
+	r.resources[resource.ID] = cloneResource(*resource)
+	return nil
+}
+
+// GetResource retrieves a resource by ID
+func (r *MemoryRepository) GetResource(ctx context.Context, id string) (*domain.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resource, ok := r.resources[id]
+	if !ok {
+		return nil, fmt.Errorf("get resource: %w", ErrNotFound)
+	}
+	resource = cloneResource(resource)
+	return &resource, nil
+}
+
+// ListResources returns all resources ordered by name
+func (r *MemoryRepository) ListResources(ctx context.Context) ([]domain.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resources := make([]domain.Resource, 0, len(r.resources))
+	for _, resource := range r.resources {
+		resources = append(resources, cloneResource(resource))
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Name != resources[j].Name {
+			return resources[i].Name < resources[j].Name
+		}
+		return resources[i].ID < resources[j].ID
+	})
+	return resources, nil
+}
+
+// calendarEvents returns copies of the owner's events overlapping [start,
+// end), ordered by start time. Callers must hold r.mu.
+func (r *MemoryRepository) calendarEvents(o owner, start, end time.Time) []domain.CalendarEvent {
+	c, ok := r.calendars[o]
+	if !ok {
+		return nil
+	}
+	ids := c.overlapping(r.events, start, end)
+	events := make([]domain.CalendarEvent, 0, len(ids))
+	for _, id := range ids {
+		events = append(events, cloneEvent(r.events[id]))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
+
+// checkAvailability returns ErrConflict if any of the owners has an event
+// overlapping one of the meeting's occurrences, including the event's travel
+// time and the buffers, ignoring the meeting's own events. Callers must hold
+// r.mu.
+func (r *MemoryRepository) checkAvailability(owners []owner, meeting *domain.Meeting) error {
+	occurrences, err := meetingOccurrences(meeting)
+	if err != nil || len(occurrences) == 0 {
+		return err
+	}
+	start := occurrences[0].StartTime.Add(-maxPadding)
+	end := occurrences[len(occurrences)-1].EndTime.Add(maxPadding)
+
+	var events []domain.CalendarEvent
+	checked := make(map[owner]bool, len(owners))
+	for _, o := range owners {
+		if checked[o] {
+			continue
+		}
+		checked[o] = true
+		for _, event := range r.calendarEvents(o, start, end) {
+			if event.MeetingID == nil || *event.MeetingID != meeting.ID {
+				events = append(events, event)
+			}
+		}
+	}
+	return checkOccurrences(occurrences, events, padding(meeting, r.users))
+}
+
+// participants returns the users who still attend the meeting. Callers must
+// hold r.mu.
+func (r *MemoryRepository) participants(meetingID string) []string {
+	var userIDs []string
+	for _, eventID := range r.meetingEvents[meetingID] {
+		if event := r.events[eventID]; event.ResourceID == nil {
+			userIDs = append(userIDs, event.UserID)
+		}
+	}
+	return userIDs
+}
+
+// deleteMeeting removes a meeting and its events. Callers must hold r.mu.
+func (r *MemoryRepository) deleteMeeting(id string) {
+	for _, eventID := range append([]string(nil), r.meetingEvents[id]...) {
+		r.removeEvent(r.events[eventID])
+	}
+	delete(r.meetingEvents, id)
+	delete(r.meetings, id)
+}
+
+// addEvent stores a new event and indexes it. Callers must hold r.mu.
+func (r *MemoryRepository) addEvent(event domain.CalendarEvent) {
+	r.events[event.ID] = event
+	r.index(event)
+	if event.MeetingID != nil {
+		r.meetingEvents[*event.MeetingID] = append(r.meetingEvents[*event.MeetingID], event.ID)
+	}
+}
+
+// removeEvent deletes a stored event and its index entries. Callers must
+// hold r.mu.
+func (r *MemoryRepository) removeEvent(event domain.CalendarEvent) {
+	delete(r.events, event.ID)
+	r.unindex(event)
+	if event.MeetingID != nil {
+		ids := r.meetingEvents[*event.MeetingID]
+		for i, id := range ids {
+			if id == event.ID {
+				r.meetingEvents[*event.MeetingID] = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// saveEvent creates the event or replaces the stored event with the same ID.
+// Callers must hold r.mu.
+func (r *MemoryRepository) saveEvent(event domain.CalendarEvent) {
+	stored, ok := r.events[event.ID]
+	if !ok {
+		r.addEvent(event)
+		return
+	}
+	if !sameString(stored.MeetingID, event.MeetingID) {
+		r.removeEvent(stored)
+		r.addEvent(event)
+		return
+	}
+	// Keep the event's place among its meeting's events
+	r.unindex(stored)
+	r.events[event.ID] = event
+	r.index(event)
+}
+
+// index adds the event to its owner's calendar. Callers must hold r.mu.
+func (r *MemoryRepository) index(event domain.CalendarEvent) {
+	o := ownerOf(event)
+	c, ok := r.calendars[o]
+	if !ok {
+		c = newCalendar()
+		r.calendars[o] = c
+	}
+	c.insert(event)
+}
+
+// unindex removes the event from its owner's calendar. Callers must hold r.mu.
+func (r *MemoryRepository) unindex(event domain.CalendarEvent) {
+	if c, ok := r.calendars[ownerOf(event)]; ok {
+		c.remove(event)
+	}
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// cloneEvent copies an event, so that callers cannot change stored events
+// through shared pointers and slices
+func cloneEvent(event domain.CalendarEvent) domain.CalendarEvent {
+	event.MeetingID = cloneString(event.MeetingID)
+	event.ResourceID = cloneString(event.ResourceID)
+	event.Exceptions = cloneTimes(event.Exceptions)
+	event.RecurrenceEnd = cloneTime(event.RecurrenceEnd)
+	event.RecurrenceID = cloneTime(event.RecurrenceID)
+	return event
+}
+
+// cloneMeeting copies a meeting without its participants and events, which
+// are derived from the stored events
+func cloneMeeting(meeting domain.Meeting) domain.Meeting {
+	meeting.Exceptions = cloneTimes(meeting.Exceptions)
+	meeting.RecurrenceEnd = cloneTime(meeting.RecurrenceEnd)
+	meeting.ParticipantIDs = nil
+	meeting.ResourceIDs = nil
+	meeting.Events = nil
+	return meeting
+}
+
+func cloneResource(resource domain.Resource) domain.Resource {
+	if resource.Attributes != nil {
+		resource.Attributes = append([]string(nil), resource.Attributes...)
+	}
+	return resource
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func cloneTimes(times []time.Time) []time.Time {
+	if times == nil {
+		return nil
+	}
+	return append([]time.Time(nil), times...)
+}
@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meeting-scheduler/internal/domain"
+)
+
+var day = time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+
+func at(hour int) time.Time {
+	return day.Add(time.Duration(hour) * time.Hour)
+}
+
+func eventIDs(events []domain.CalendarEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func newTestRepository(t *testing.T, userIDs ...string) *MemoryRepository {
+	t.Helper()
+	repo := NewMemoryRepository()
+	for _, id := range userIDs {
+		if err := repo.CreateUser(context.Background(), &domain.User{ID: id, Name: id}); err != nil {
+			t.Fatalf("Failed to create user %s: %v", id, err)
+		}
+	}
+	return repo
+}
+
+func TestMemoryGetUserEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, "user1", "user2")
+	seriesEnd := at(-24*6 + 1)
+	events := []*domain.CalendarEvent{
+		{ID: "early", StartTime: at(7), EndTime: at(8), UserID: "user1"},
+		{ID: "spanning", StartTime: at(8), EndTime: at(10), UserID: "user1"},
+		{ID: "inside", StartTime: at(11), EndTime: at(12), UserID: "user1"},
+		{ID: "late", StartTime: at(13), EndTime: at(18), UserID: "user1"},
+		{ID: "after", StartTime: at(14), EndTime: at(15), UserID: "user1"},
+		{ID: "long", StartTime: at(-48), EndTime: at(-24), UserID: "user1"},
+		{ID: "daily", StartTime: at(-24 * 7), EndTime: at(-24*7 + 1), UserID: "user1", Recurrence: "FREQ=DAILY"},
+		{ID: "ended", StartTime: at(-24 * 7), EndTime: at(-24*7 + 1), UserID: "user1", Recurrence: "FREQ=DAILY;COUNT=2", RecurrenceEnd: &seriesEnd},
+		{ID: "other", StartTime: at(9), EndTime: at(10), UserID: "user2"},
+	}
+	for _, event := range events {
+		if err := repo.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event %s: %v", event.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		expected   []string
+	}{
+		{name: "events touching the window are left out", start: at(8), end: at(13), expected: []string{"daily", "inside", "spanning"}},
+		{name: "events running into the window", start: at(9), end: at(14), expected: []string{"daily", "inside", "late", "spanning"}},
+		{name: "window inside an event", start: at(15), end: at(16), expected: []string{"daily", "late"}},
+		{name: "long event before a short one", start: at(-30), end: at(-29), expected: []string{"daily", "long"}},
+		{name: "ended series", start: at(-24 * 4), end: at(-24 * 3), expected: []string{"daily"}},
+		{name: "before any event", start: at(-24 * 8), end: at(-24 * 7), expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetUserEvents(ctx, "user1", tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ids := eventIDs(got); !equalIDs(ids, tt.expected) {
+				t.Errorf("Expected events %v, got %v", tt.expected, ids)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].StartTime.Before(got[i-1].StartTime) {
+					t.Errorf("Events are not sorted by start time: %v", eventIDs(got))
+				}
+			}
+		})
+	}
+
+	// Moving an event updates the index
+	moved := *events[4]
+	moved.StartTime, moved.EndTime = at(-3), at(-2)
+	if err := repo.UpdateEvent(ctx, &moved); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, err := repo.GetUserEvents(ctx, "user1", at(-4), at(-1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := eventIDs(got); !equalIDs(ids, []string{"after", "daily"}) {
+		t.Errorf("Expected the moved event, got %v", ids)
+	}
+
+	byUser, err := repo.GetEventsForUsers(ctx, []string{"user1", "user2", "ghost"}, at(9), at(10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := eventIDs(byUser["user2"]); !equalIDs(ids, []string{"other"}) {
+		t.Errorf("Expected user2's event, got %v", ids)
+	}
+	if ids := eventIDs(byUser["user1"]); !equalIDs(ids, []string{"daily", "spanning"}) {
+		t.Errorf("Expected user1's event, got %v", ids)
+	}
+	if len(byUser["ghost"]) != 0 {
+		t.Errorf("Expected no events for an unknown user, got %v", byUser["ghost"])
+	}
+
+	room, projector := "room", "projector"
+	for _, event := range []*domain.CalendarEvent{
+		{ID: "room-booking", StartTime: at(9), EndTime: at(10), ResourceID: &room},
+		{ID: "projector-booking", StartTime: at(9), EndTime: at(11), ResourceID: &projector},
+		{ID: "room-later", StartTime: at(12), EndTime: at(13), ResourceID: &room},
+	} {
+		if err := repo.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event %s: %v", event.ID, err)
+		}
+	}
+	byResource, err := repo.GetEventsForResources(ctx, []string{"room", "projector", "user1"}, at(9), at(10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := eventIDs(byResource["room"]); !equalIDs(ids, []string{"room-booking"}) {
+		t.Errorf("Expected the room's booking, got %v", ids)
+	}
+	if ids := eventIDs(byResource["projector"]); !equalIDs(ids, []string{"projector-booking"}) {
+		t.Errorf("Expected the projector's booking, got %v", ids)
+	}
+	if len(byResource["user1"]) != 0 {
+		t.Errorf("Expected no user events among the resources, got %v", eventIDs(byResource["user1"]))
+	}
+}
+
+func TestMemoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, "user1")
+	event := &domain.CalendarEvent{ID: "event", Title: "Focus", StartTime: at(9), EndTime: at(10), UserID: "user1"}
+	if err := repo.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	event.Title = "Changed"
+
+	stored, err := repo.GetEvent(ctx, "event")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stored.Title != "Focus" {
+		t.Errorf("Expected the stored event to keep its title, got %q", stored.Title)
+	}
+	stored.Title = "Changed"
+	if again, _ := repo.GetEvent(ctx, "event"); again.Title != "Focus" {
+		t.Errorf("Expected the stored event to keep its title, got %q", again.Title)
+	}
+}
+
+func TestMemoryNotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	if _, err := repo.GetUser(ctx, "ghost"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v for GetUser, got %v", ErrNotFound, err)
+	}
+	if _, err := repo.GetEvent(ctx, "ghost"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v for GetEvent, got %v", ErrNotFound, err)
+	}
+	if _, err := repo.GetMeeting(ctx, "ghost"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v for GetMeeting, got %v", ErrNotFound, err)
+	}
+	if _, err := repo.GetResource(ctx, "ghost"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v for GetResource, got %v", ErrNotFound, err)
+	}
+
+	// Like the MySQL repository, deleting a missing record is not an error
+	if err := repo.DeleteEvent(ctx, "ghost"); err != nil {
+		t.Errorf("Unexpected error for DeleteEvent: %v", err)
+	}
+
+	users, err := repo.GetUsers(ctx, []string{"ghost"})
+	if err != nil || len(users) != 0 {
+		t.Errorf("Expected no users and no error, got %v, %v", users, err)
+	}
+}
+
+func TestMemoryCreateMeetingWithEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, "user1", "user2")
+	if err := repo.CreateEvent(ctx, &domain.CalendarEvent{ID: "busy", StartTime: at(12), EndTime: at(13), UserID: "user2"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	book := func(id string, start, end time.Time) error {
+		meetingID := id
+		meeting := &domain.Meeting{ID: id, OrganizerID: "user1", StartTime: start, EndTime: end}
+		return repo.CreateMeetingWithEvents(ctx, meeting, []*domain.CalendarEvent{
+			{ID: id + "-1", StartTime: start, EndTime: end, UserID: "user1", MeetingID: &meetingID},
+			{ID: id + "-2", StartTime: start, EndTime: end, UserID: "user2", MeetingID: &meetingID},
+		})
+	}
+
+	if err := book("standup", at(9), at(10)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	meeting, err := repo.GetMeeting(ctx, "standup")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(meeting.ParticipantIDs)
+	if !equalIDs(meeting.ParticipantIDs, []string{"user1", "user2"}) {
+		t.Errorf("Expected both participants, got %v", meeting.ParticipantIDs)
+	}
+
+	if err := book("clash", at(12), at(14)); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected %v, got %v", ErrConflict, err)
+	}
+	if _, err := repo.GetMeeting(ctx, "clash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the conflicting meeting not to be stored, got %v", err)
+	}
+	if events, _ := repo.GetUserEvents(ctx, "user1", at(12), at(14)); len(events) != 0 {
+		t.Errorf("Expected no events from the conflicting meeting, got %v", eventIDs(events))
+	}
+
+	// Moving the meeting onto user2's event conflicts too
+	meeting.StartTime, meeting.EndTime = at(12), at(13)
+	if err := repo.UpdateMeetingTime(ctx, meeting); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected %v, got %v", ErrConflict, err)
+	}
+	meeting.StartTime, meeting.EndTime = at(15), at(16)
+	if err := repo.UpdateMeetingTime(ctx, meeting); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	events, err := repo.GetUserEvents(ctx, "user2", at(15), at(16))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := eventIDs(events); !equalIDs(ids, []string{"standup-2"}) {
+		t.Errorf("Expected the meeting's event to move, got %v", ids)
+	}
+
+	if err := repo.DeleteMeeting(ctx, "standup"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := repo.GetEvent(ctx, "standup-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the meeting's events to be deleted, got %v", err)
+	}
+}
+
+func TestMemoryConflictsIncludeTravelAndBuffers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	if err := repo.CreateUser(ctx, &domain.User{ID: "user1", Name: "user1", BufferAfterMinutes: 30}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := repo.CreateEvent(ctx, &domain.CalendarEvent{ID: "offsite", StartTime: at(12), EndTime: at(13), UserID: "user1", TravelMinutes: 60})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	book := func(id string, start, end time.Time, bufferBefore, bufferAfter int) error {
+		meeting := &domain.Meeting{ID: id, OrganizerID: "user1", StartTime: start, EndTime: end, BufferBeforeMinutes: bufferBefore, BufferAfterMinutes: bufferAfter}
+		return repo.CreateMeetingWithEvents(ctx, meeting, []*domain.CalendarEvent{domain.NewMeetingEvent(meeting, "user1")})
+	}
+
+	tests := []struct {
+		name         string
+		start, end   time.Time
+		bufferBefore int
+		bufferAfter  int
+		conflict     bool
+	}{
+		{name: "during the travel time", start: at(13), end: at(14), conflict: true},
+		{name: "in the user's buffer before the travel time", start: at(10), end: at(10).Add(45 * time.Minute), conflict: true},
+		{name: "clear of the buffer and travel time", start: at(10), end: at(10).Add(30 * time.Minute)},
+		{name: "in the meeting's buffer after the travel time", start: at(14), end: at(15), bufferBefore: 15, conflict: true},
+		{name: "clear of the meeting's buffer", start: at(14).Add(15 * time.Minute), end: at(15), bufferBefore: 15, bufferAfter: 60},
+		{name: "in the buffer a meeting was booked with", start: at(15).Add(30 * time.Minute), end: at(16), conflict: true},
+		{name: "clear of the buffer a meeting was booked with", start: at(16), end: at(16).Add(30 * time.Minute)},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := book(string(rune('a'+i)), tt.start, tt.end, tt.bufferBefore, tt.bufferAfter)
+			if tt.conflict && !errors.Is(err, ErrConflict) {
+				t.Errorf("Expected %v, got %v", ErrConflict, err)
+			}
+			if !tt.conflict && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMemoryDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, "user1", "user2", "user3")
+
+	book := func(id string, start time.Time, participants ...string) {
+		t.Helper()
+		meetingID := id
+		end := start.Add(time.Hour)
+		meeting := &domain.Meeting{ID: id, OrganizerID: participants[0], StartTime: start, EndTime: end}
+		var events []*domain.CalendarEvent
+		for _, userID := range participants {
+			events = append(events, &domain.CalendarEvent{
+				ID: id + "-" + userID, StartTime: start, EndTime: end, UserID: userID, MeetingID: &meetingID,
+			})
+		}
+		if err := repo.CreateMeetingWithEvents(ctx, meeting, events); err != nil {
+			t.Fatalf("Failed to book %s: %v", id, err)
+		}
+	}
+	book("team", at(9), "user1", "user3", "user2")
+	book("solo", at(11), "user1")
+
+	if err := repo.DeleteUser(ctx, "user1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := repo.GetUser(ctx, "user1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the user to be deleted, got %v", err)
+	}
+	if _, err := repo.GetEvent(ctx, "team-user1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the user's events to be deleted, got %v", err)
+	}
+	if _, err := repo.GetMeeting(ctx, "solo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the meeting without participants to be deleted, got %v", err)
+	}
+
+	meeting, err := repo.GetMeeting(ctx, "team")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meeting.OrganizerID != "user2" {
+		t.Errorf("Expected the meeting to pass to user2, got %q", meeting.OrganizerID)
+	}
+	sort.Strings(meeting.ParticipantIDs)
+	if !equalIDs(meeting.ParticipantIDs, []string{"user2", "user3"}) {
+		t.Errorf("Expected the remaining participants, got %v", meeting.ParticipantIDs)
+	}
+}
+
+func TestMemoryConcurrentBookings(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, "user1")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			meetingID := string(rune('a' + i))
+			meeting := &domain.Meeting{ID: meetingID, OrganizerID: "user1", StartTime: at(9), EndTime: at(10)}
+			errs[i] = repo.CreateMeetingWithEvents(ctx, meeting, []*domain.CalendarEvent{
+				{ID: meetingID + "-event", StartTime: at(9), EndTime: at(10), UserID: "user1", MeetingID: &meetingID},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	booked := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case !errors.Is(err, ErrConflict):
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if booked != 1 {
+		t.Errorf("Expected exactly one booking to succeed, got %d", booked)
+	}
+}
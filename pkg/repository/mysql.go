@@ -2,13 +2,24 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/meeting-scheduler/internal/domain"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// overlappingEvents matches single events overlapping [start, end) and
+// recurring events whose series does, taking the arguments end, start, start
+const overlappingEvents = "start_time < ? AND (end_time > ? OR (recurrence <> '' AND (recurrence_end IS NULL OR recurrence_end > ?)))"
+
+// readCommitted is the isolation level of transactions that check calendars
+// for conflicts. Once they hold the owners' row locks, every read sees the
+// events committed by the transactions that held the locks before them.
+var readCommitted = &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+
 type MySQLRepository struct {
 	db *gorm.DB
 }
@@ -21,7 +32,7 @@ func NewMySQLRepository(dsn string) (*MySQLRepository, error) {
 	}
 
 	// Auto migrate the schema
-	err = db.AutoMigrate(&domain.User{}, &domain.CalendarEvent{})
+	err = db.AutoMigrate(&domain.User{}, &domain.Resource{}, &domain.Meeting{}, &domain.CalendarEvent{})
 	if err != nil {
 		return nil, err
 	}
@@ -36,31 +47,466 @@ func (r *MySQLRepository) GetUser(ctx context.Context, id string) (*domain.User,
 	var user domain.User
 	result := r.db.WithContext(ctx).First(&user, "id = ?", id)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, translate("get user", result.Error)
 	}
 	return &user, nil
 }
 
-// GetUserEvents retrieves a user's calendar events within a time range
+// GetUsers retrieves the users with the given IDs in a single query. Users
+// that do not exist are left out.
+func (r *MySQLRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	var users []domain.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	result := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users)
+	if result.Error != nil {
+		return nil, translate("get users", result.Error)
+	}
+	return users, nil
+}
+
+// GetUserEvents retrieves a user's calendar events that overlap a time range,
+// including events that start before or end after it. Recurring events are
+// returned unexpanded if the series runs into the time range.
 func (r *MySQLRepository) GetUserEvents(ctx context.Context, userID string, start, end time.Time) ([]domain.CalendarEvent, error) {
 	var events []domain.CalendarEvent
 	result := r.db.WithContext(ctx).
-		Where("user_id = ? AND start_time >= ? AND end_time <= ?", userID, start, end).
+		Where("user_id = ?", userID).
+		Where(overlappingEvents, end, start, start).
 		Find(&events)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, translate("get user events", result.Error)
 	}
 	return events, nil
 }
 
-// CreateEvent creates a new calendar event
+// GetEventsForUsers retrieves the events of several users that overlap a
+// time range in a single query, keyed by user ID, with the same semantics as
+// GetUserEvents
+func (r *MySQLRepository) GetEventsForUsers(ctx context.Context, userIDs []string, start, end time.Time) (map[string][]domain.CalendarEvent, error) {
+	byUser := make(map[string][]domain.CalendarEvent, len(userIDs))
+	if len(userIDs) == 0 {
+		return byUser, nil
+	}
+	var events []domain.CalendarEvent
+	result := r.db.WithContext(ctx).
+		Where("user_id IN ?", userIDs).
+		Where(overlappingEvents, end, start, start).
+		Find(&events)
+	if result.Error != nil {
+		return nil, translate("get events for users", result.Error)
+	}
+	for _, event := range events {
+		byUser[event.UserID] = append(byUser[event.UserID], event)
+	}
+	return byUser, nil
+}
+
+// GetEventsForResources retrieves the events booking several resources that
+// overlap a time range, keyed by resource ID, with one query
+func (r *MySQLRepository) GetEventsForResources(ctx context.Context, resourceIDs []string, start, end time.Time) (map[string][]domain.CalendarEvent, error) {
+	byResource := make(map[string][]domain.CalendarEvent, len(resourceIDs))
+	if len(resourceIDs) == 0 {
+		return byResource, nil
+	}
+	var events []domain.CalendarEvent
+	result := r.db.WithContext(ctx).
+		Where("resource_id IN ?", resourceIDs).
+		Where(overlappingEvents, end, start, start).
+		Find(&events)
+	if result.Error != nil {
+		return nil, translate("get events for resources", result.Error)
+	}
+	for _, event := range events {
+		byResource[*event.ResourceID] = append(byResource[*event.ResourceID], event)
+	}
+	return byResource, nil
+}
+
+// CreateEvent creates a new calendar event. The user's row is locked first,
+// so that bookings checking the user's calendar see the event.
 func (r *MySQLRepository) CreateEvent(ctx context.Context, event *domain.CalendarEvent) error {
-	return r.db.WithContext(ctx).Create(event).Error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUsers(tx, []string{event.UserID}); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+	return translate("create event", err)
+}
+
+// GetEvent retrieves a calendar event by ID
+func (r *MySQLRepository) GetEvent(ctx context.Context, id string) (*domain.CalendarEvent, error) {
+	var event domain.CalendarEvent
+	result := r.db.WithContext(ctx).First(&event, "id = ?", id)
+	if result.Error != nil {
+		return nil, translate("get event", result.Error)
+	}
+	return &event, nil
+}
+
+// UpdateEvent saves changes to an existing calendar event, locking the user's
+// row first like CreateEvent
+func (r *MySQLRepository) UpdateEvent(ctx context.Context, event *domain.CalendarEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUsers(tx, []string{event.UserID}); err != nil {
+			return err
+		}
+		return tx.Save(event).Error
+	})
+	return translate("update event", err)
+}
+
+// DeleteEvent removes a calendar event
+func (r *MySQLRepository) DeleteEvent(ctx context.Context, id string) error {
+	return translate("delete event", r.db.WithContext(ctx).Delete(&domain.CalendarEvent{}, "id = ?", id).Error)
+}
+
+// ImportEvents stores events imported into the user's calendar in a single
+// transaction. Events whose UID the user already has replace the stored event,
+// keeping its ID and creation time; the others are created. The user's events
+// with one of the removed UIDs are deleted. Like CreateEvent it locks the
+// user's row first. It returns how many events were created and deleted.
+func (r *MySQLRepository) ImportEvents(ctx context.Context, userID string, events []*domain.CalendarEvent, removedUIDs []string) (int, int, error) {
+	created, removed := 0, 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUsers(tx, []string{userID}); err != nil {
+			return err
+		}
+		if len(removedUIDs) > 0 {
+			result := tx.Where("user_id = ? AND uid IN ?", userID, removedUIDs).Delete(&domain.CalendarEvent{})
+			if result.Error != nil {
+				return result.Error
+			}
+			removed = int(result.RowsAffected)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		uids := make([]string, 0, len(events))
+		for _, event := range events {
+			uids = append(uids, event.UID)
+		}
+		var existing []domain.CalendarEvent
+		if err := tx.Where("user_id = ? AND uid IN ?", userID, uids).Find(&existing).Error; err != nil {
+			return err
+		}
+		byUID := make(map[string]domain.CalendarEvent, len(existing))
+		for _, event := range existing {
+			byUID[event.UID] = event
+		}
+
+		created = 0
+		for _, event := range events {
+			if stored, ok := byUID[event.UID]; ok {
+				event.ID = stored.ID
+				event.CreatedAt = stored.CreatedAt
+			} else {
+				created++
+			}
+			if err := tx.Save(event).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return created, removed, translate("import events", err)
+}
+
+// CreateMeetingWithEvents creates a meeting together with its participants'
+// and resources' events in a single transaction, so either all of them are
+// stored or none are. The participants' and resources' rows are locked and
+// their calendars re-checked inside the transaction; ErrConflict is returned
+// if the slot has been taken meanwhile.
+func (r *MySQLRepository) CreateMeetingWithEvents(ctx context.Context, meeting *domain.Meeting, events []*domain.CalendarEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		userIDs, resourceIDs := eventOwners(events)
+		if err := lockAndCheckAvailability(tx, userIDs, resourceIDs, meeting); err != nil {
+			return err
+		}
+		if err := tx.Omit("Events").Create(meeting).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Create(events).Error
+	}, readCommitted)
+	return translate("create meeting", err)
+}
+
+// GetMeeting retrieves a meeting by ID along with its participants and resources
+func (r *MySQLRepository) GetMeeting(ctx context.Context, id string) (*domain.Meeting, error) {
+	var meeting domain.Meeting
+	result := r.db.WithContext(ctx).Preload("Events").First(&meeting, "id = ?", id)
+	if result.Error != nil {
+		return nil, translate("get meeting", result.Error)
+	}
+	meeting.ParticipantIDs = make([]string, 0, len(meeting.Events))
+	for _, event := range meeting.Events {
+		if event.ResourceID != nil {
+			meeting.ResourceIDs = append(meeting.ResourceIDs, *event.ResourceID)
+			continue
+		}
+		meeting.ParticipantIDs = append(meeting.ParticipantIDs, event.UserID)
+	}
+	return &meeting, nil
+}
+
+// DeleteMeeting removes a meeting and all of its participants' events
+func (r *MySQLRepository) DeleteMeeting(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("meeting_id = ?", id).Delete(&domain.CalendarEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Meeting{}, "id = ?", id).Error
+	})
+	return translate("delete meeting", err)
+}
+
+// UpdateMeetingTime moves a meeting and all of its participants' and
+// resources' events to the meeting's new start and end time, along with the
+// exceptions and end of a recurring series and, for the participants, the
+// buffers it is now booked with. Like CreateMeetingWithEvents it
+// returns ErrConflict if one of the participants or resources has been booked
+// in the new slot meanwhile.
+func (r *MySQLRepository) UpdateMeetingTime(ctx context.Context, meeting *domain.Meeting) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the meeting's events serializes concurrent reschedules of
+		// the meeting
+		var events []*domain.CalendarEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("meeting_id = ?", meeting.ID).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+		userIDs, resourceIDs := eventOwners(events)
+		if err := lockAndCheckAvailability(tx, userIDs, resourceIDs, meeting); err != nil {
+			return err
+		}
+		columns := []string{"start_time", "end_time", "exceptions", "recurrence_end"}
+		buffers := []string{"buffer_before_minutes", "buffer_after_minutes"}
+		err = tx.Model(&domain.Meeting{}).Where("id = ?", meeting.ID).Select(append(columns, buffers...)).Updates(&domain.Meeting{
+			StartTime:           meeting.StartTime,
+			EndTime:             meeting.EndTime,
+			Exceptions:          meeting.Exceptions,
+			RecurrenceEnd:       meeting.RecurrenceEnd,
+			BufferBeforeMinutes: meeting.BufferBeforeMinutes,
+			BufferAfterMinutes:  meeting.BufferAfterMinutes,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&domain.CalendarEvent{}).Where("meeting_id = ?", meeting.ID).Select(columns).Updates(&domain.CalendarEvent{
+			StartTime:     meeting.StartTime,
+			EndTime:       meeting.EndTime,
+			Exceptions:    meeting.Exceptions,
+			RecurrenceEnd: meeting.RecurrenceEnd,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&domain.CalendarEvent{}).Where("meeting_id = ? AND resource_id IS NULL", meeting.ID).Select(buffers).Updates(&domain.CalendarEvent{
+			BufferBeforeMinutes: meeting.BufferBeforeMinutes,
+			BufferAfterMinutes:  meeting.BufferAfterMinutes,
+		}).Error
+	}, readCommitted)
+	return translate("update meeting time", err)
+}
+
+// SetMeetingExceptions replaces the exceptions of a recurring meeting and of
+// all of its participants' events
+func (r *MySQLRepository) SetMeetingExceptions(ctx context.Context, id string, exceptions []time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Meeting{}).Where("id = ?", id).Select("exceptions").
+			Updates(&domain.Meeting{Exceptions: exceptions}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&domain.CalendarEvent{}).Where("meeting_id = ?", id).Select("exceptions").
+			Updates(&domain.CalendarEvent{Exceptions: exceptions}).Error
+	})
+	return translate("set meeting exceptions", err)
+}
+
+// lockAndCheckAvailability takes row locks on the given users and resources
+// (in ID order, so concurrent transactions cannot deadlock) and reports
+// ErrConflict if any of them already has an event overlapping one of the
+// meeting's occurrences, including the event's travel time and the buffers,
+// ignoring the meeting's own events
+func lockAndCheckAvailability(tx *gorm.DB, userIDs, resourceIDs []string, meeting *domain.Meeting) error {
+	if len(userIDs) == 0 && len(resourceIDs) == 0 {
+		return nil
+	}
+
+	occurrences, err := meetingOccurrences(meeting)
+	if err != nil || len(occurrences) == 0 {
+		return err
+	}
+	start := occurrences[0].StartTime.Add(-maxPadding)
+	end := occurrences[len(occurrences)-1].EndTime.Add(maxPadding)
+
+	users, err := lockUsers(tx, userIDs)
+	if err != nil {
+		return err
+	}
+	if len(resourceIDs) > 0 {
+		var resources []domain.Resource
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", resourceIDs).
+			Order("id").
+			Find(&resources).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// The transaction runs at READ COMMITTED, so this plain read sees every
+	// event committed before the locks were granted. Resource events store an
+	// empty user_id, so only user events are matched by user.
+	var events []domain.CalendarEvent
+	err = tx.Where("((resource_id IS NULL AND user_id IN ?) OR resource_id IN ?)", nonEmpty(userIDs), nonEmpty(resourceIDs)).
+		Where(overlappingEvents, end, start, start).
+		Where("(meeting_id IS NULL OR meeting_id <> ?)", meeting.ID).
+		Find(&events).Error
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]domain.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return checkOccurrences(occurrences, events, padding(meeting, byID))
+}
+
+// lockUsers takes row locks on the users, in ID order so that concurrent
+// transactions cannot deadlock, and returns them
+func lockUsers(tx *gorm.DB, ids []string) ([]domain.User, error) {
+	var users []domain.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", nonEmpty(ids)).
+		Order("id").
+		Find(&users).Error
+	return users, err
+}
+
+// eventOwners returns the users and the resources the events belong to
+func eventOwners(events []*domain.CalendarEvent) (userIDs, resourceIDs []string) {
+	for _, event := range events {
+		if event.ResourceID != nil {
+			resourceIDs = append(resourceIDs, *event.ResourceID)
+		} else {
+			userIDs = append(userIDs, event.UserID)
+		}
+	}
+	return userIDs, resourceIDs
+}
+
+// nonEmpty keeps an IN condition valid for an empty list of IDs. No user or
+// resource has an empty ID, but resource events have an empty user_id.
+func nonEmpty(ids []string) []string {
+	if len(ids) == 0 {
+		return []string{""}
+	}
+	return ids
 }
 
 // CreateUser creates a new user
 func (r *MySQLRepository) CreateUser(ctx context.Context, user *domain.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return translate("create user", r.db.WithContext(ctx).Create(user).Error)
+}
+
+// ListUsers retrieves all users ordered by name
+func (r *MySQLRepository) ListUsers(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+	result := r.db.WithContext(ctx).Order("name, id").Find(&users)
+	if result.Error != nil {
+		return nil, translate("list users", result.Error)
+	}
+	return users, nil
+}
+
+// UpdateUser saves changes to an existing user
+func (r *MySQLRepository) UpdateUser(ctx context.Context, user *domain.User) error {
+	return translate("update user", r.db.WithContext(ctx).Save(user).Error)
+}
+
+// DeleteUser removes a user and their events in a single transaction. Meetings
+// the user attended lose them as a participant; meetings left without any
+// participant are deleted and meetings they organized are handed to the
+// remaining participant with the lowest ID.
+func (r *MySQLRepository) DeleteUser(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var meetingIDs []string
+		err := tx.Model(&domain.CalendarEvent{}).
+			Where("user_id = ? AND meeting_id IS NOT NULL", id).
+			Distinct().
+			Pluck("meeting_id", &meetingIDs).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&domain.CalendarEvent{}).Error; err != nil {
+			return err
+		}
+
+		if len(meetingIDs) > 0 {
+			var abandoned []string
+			err = tx.Model(&domain.Meeting{}).
+				Where("id IN ? AND NOT EXISTS (SELECT 1 FROM calendar_events WHERE calendar_events.meeting_id = meetings.id AND calendar_events.resource_id IS NULL)", meetingIDs).
+				Pluck("id", &abandoned).Error
+			if err != nil {
+				return err
+			}
+			if len(abandoned) > 0 {
+				// Release the resources booked for meetings nobody attends anymore
+				if err := tx.Where("meeting_id IN ?", abandoned).Delete(&domain.CalendarEvent{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("id IN ?", abandoned).Delete(&domain.Meeting{}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		err = tx.Exec(`UPDATE meetings SET organizer_id = (
+			SELECT MIN(user_id) FROM calendar_events WHERE calendar_events.meeting_id = meetings.id AND calendar_events.resource_id IS NULL
+		) WHERE organizer_id = ?`, id).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&domain.User{}, "id = ?", id).Error
+	})
+	return translate("delete user", err)
+}
+
+// CreateResource creates a new resource
+func (r *MySQLRepository) CreateResource(ctx context.Context, resource *domain.Resource) error {
+	return translate("create resource", r.db.WithContext(ctx).Create(resource).Error)
+}
+
+// GetResource retrieves a resource by ID
+func (r *MySQLRepository) GetResource(ctx context.Context, id string) (*domain.Resource, error) {
+	var resource domain.Resource
+	result := r.db.WithContext(ctx).First(&resource, "id = ?", id)
+	if result.Error != nil {
+		return nil, translate("get resource", result.Error)
+	}
+	return &resource, nil
+}
+
+// ListResources returns all resources ordered by name
+func (r *MySQLRepository) ListResources(ctx context.Context) ([]domain.Resource, error) {
+	var resources []domain.Resource
+	result := r.db.WithContext(ctx).Order("name, id").Find(&resources)
+	if result.Error != nil {
+		return nil, translate("list resources", result.Error)
+	}
+	return resources, nil
 }
 
 // ClearAllData removes all data from the database (useful for testing)
@@ -69,6 +515,14 @@ func (r *MySQLRepository) ClearAllData(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	err = r.db.WithContext(ctx).Exec("DELETE FROM meetings").Error
+	if err != nil {
+		return err
+	}
+	err = r.db.WithContext(ctx).Exec("DELETE FROM resources").Error
+	if err != nil {
+		return err
+	}
 	return r.db.WithContext(ctx).Exec("DELETE FROM users").Error
 }
 